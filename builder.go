@@ -8,6 +8,8 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -19,6 +21,12 @@ type Builder interface {
 	// Definitions are added to the map under their respective names.
 	AddDir(dir string) error
 
+	// Adds an entire directory as schemas in the same way as AddDir,
+	// but continues past any files which fail to be added rather than aborting.
+	// A file which fails to be added adds none of its schemas, including its definitions.
+	// Returns the paths of the files which were added, and a mapping of path to error for those which failed.
+	AddDirLenient(dir string) (added []string, errs map[string]error)
+
 	// Opens the file and adds it to the schema map.
 	// The root schema will be added to the map under the file name.
 	// Definitions are added to the map under their respective names.
@@ -26,12 +34,29 @@ type Builder interface {
 
 	// Adds a schema to the schema map as 'name'
 	// Definitions are added to the map under their respective names.
+	// If an error is returned, neither the schema nor any of its definitions are added.
 	AddSchema(name string, schema interface{}) error
 
 	// Return a mapping of name to copies of the schemas.
 	GetSchemas() map[string][]byte
 
+	// Return a copy of the schema exactly as it was added, before its definitions were split into their own schemas.
+	// Only schemas added by AddSchema, AddFile or AddDir have an original; definitions do not.
+	GetOriginalSchema(name string) ([]byte, bool)
+
+	// Return the sorted names of all schemas which are marked with 'deprecated: true'.
+	DeprecatedSchemas() []string
+
+	// Produce a minimal json instance which is valid against the schema with the given name.
+	// Only required properties are included, and arrays contain a single item.
+	// Values are taken from 'const', 'default', 'examples', 'example' or 'enum' when available.
+	// Numbers respect 'multipleOf', and the items of arrays with 'uniqueItems' are distinct.
+	// An error is returned when no such instance can be generated, such as for a 'format' whose sample value is longer than 'maxLength'.
+	GenerateExample(name string) ([]byte, error)
+
 	// Compile all added schemas into a validator for any of the prefixs.
+	// Schemas which contain variable placeholders, or which reference such schemas,
+	// are not compiled here and may only be validated against using ValidateWithVars.
 	Compile() (Validator, error)
 }
 
@@ -41,7 +66,10 @@ type builder struct {
 
 type registeredSchema struct {
 	source             []byte
+	original           []byte
 	requiredReferences map[string]struct{}
+	deprecated         bool
+	parametric         bool
 }
 
 // Get a new bulider for creating a validator.
@@ -51,6 +79,26 @@ func NewBuilder() Builder {
 	}
 }
 
+// Validate the json in the file at 'instancePath' against the schema in the file at 'schemaPath'.
+// The schema file is added to a new builder, so it may only reference its own definitions.
+// The root schema is validated against, named by the base name of the file as with AddFile.
+func ValidateFileAgainstFile(schemaPath, instancePath string) (*gojsonschema.Result, error) {
+	b := NewBuilder()
+	if err := b.AddFile(schemaPath); err != nil {
+		return nil, err
+	}
+	v, err := b.Compile()
+	if err != nil {
+		return nil, err
+	}
+	instance, err := ioutil.ReadFile(instancePath)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to read file: "+instancePath)
+	}
+	name := filepath.Base(schemaPath)
+	return v.Validate(name[:len(name)-5], instance)
+}
+
 func (v *builder) AddDir(dir string) error {
 	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
 		name := info.Name()
@@ -65,6 +113,25 @@ func (v *builder) AddDir(dir string) error {
 	return nil
 }
 
+func (v *builder) AddDirLenient(dir string) (added []string, errs map[string]error) {
+	errs = make(map[string]error)
+	_ = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			errs[path] = err
+			return nil
+		}
+		if !info.IsDir() && strings.HasSuffix(info.Name(), ".json") {
+			if err = v.AddFile(path); err != nil {
+				errs[path] = err
+			} else {
+				added = append(added, path)
+			}
+		}
+		return nil
+	})
+	return added, errs
+}
+
 func (v *builder) AddFile(filePath string) error {
 	if filepath.Ext(filePath) != ".json" {
 		return errors.New("failed to add file as schema - file must have a .json ext")
@@ -84,23 +151,46 @@ func (v *builder) AddSchema(name string, schema interface{}) error {
 		b   json.RawMessage
 		err error
 	)
-	if _, ok := schema.(json.RawMessage); !ok {
-		if k, ok := schema.([]byte); ok {
-			b = k
-		} else if k, ok := schema.(string); ok {
-			b = json.RawMessage(k)
-		} else {
-			b, err = json.Marshal(schema)
-			if err != nil {
-				return errors.WithMessage(err, "failed to marshal schema as json")
-			}
+	if k, ok := schema.(json.RawMessage); ok {
+		b = k
+	} else if k, ok := schema.([]byte); ok {
+		b = k
+	} else if k, ok := schema.(string); ok {
+		b = json.RawMessage(k)
+	} else {
+		b, err = json.Marshal(schema)
+		if err != nil {
+			return errors.WithMessage(err, "failed to marshal schema as json")
 		}
 	}
 	var m map[string]interface{}
 	if err = json.Unmarshal(b, &m); err != nil {
 		return errors.WithMessage(err, "schema must be a correctly formatted json object")
 	}
-	return v.addSchema(name, m)
+	anchors := make(map[string]string)
+	if err = collectAnchors(name, m, "", true, anchors); err != nil {
+		return err
+	}
+	// schemas are staged until all of them have been added successfully, so that a failure adds none of them.
+	staged := make(map[string]registeredSchema)
+	if err = v.addSchema(name, m, anchors, staged); err != nil {
+		return err
+	}
+	s := staged[name]
+	s.original = append([]byte(nil), b...)
+	staged[name] = s
+	for n, s := range staged {
+		v.schemas[n] = s
+	}
+	return nil
+}
+
+func (v *builder) GetOriginalSchema(name string) ([]byte, bool) {
+	s, ok := v.schemas[name]
+	if !ok || s.original == nil {
+		return nil, false
+	}
+	return append([]byte(nil), s.original...), true
 }
 
 func (v *builder) GetSchemas() map[string][]byte {
@@ -111,6 +201,17 @@ func (v *builder) GetSchemas() map[string][]byte {
 	return out
 }
 
+func (v *builder) DeprecatedSchemas() []string {
+	out := make([]string, 0)
+	for name, s := range v.schemas {
+		if s.deprecated {
+			out = append(out, name)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
 func (v *builder) Compile() (Validator, error) {
 	schemas := make(map[string]*gojsonschema.Schema, len(v.schemas))
 
@@ -130,26 +231,26 @@ func (v *builder) Compile() (Validator, error) {
 		return nil, errors.New("missing required references: " + strings.Join(x, ", "))
 	}
 
-	for name, s := range v.schemas {
-		loader := gojsonschema.NewSchemaLoader()
-		schemasAdded := make(map[string]bool, 7)
-		schemasAdded[name] = false
-		for n := range s.requiredReferences {
-			if err := addSchemasCompile(v.schemas, &schemasAdded, loader, n); err != nil {
-				return nil, err
-			}
+	needsVars := requiresVars(v.schemas)
+	for name := range v.schemas {
+		if needsVars[name] {
+			continue
 		}
-		if schema, err := loader.Compile(gojsonschema.NewBytesLoader(SchemaRefReplace(s.source, refNameConvert))); err != nil {
-			return nil, errors.WithMessage(err, "gojsonschema: failed to compile schema with name: "+name)
+		if schema, err := compileSchema(v.schemas, name); err != nil {
+			return nil, err
 		} else {
 			schemas[name] = schema
 		}
 	}
 
-	return &validator{schemas: schemas}, nil
+	sources := make(map[string]registeredSchema, len(v.schemas))
+	for name, s := range v.schemas {
+		sources[name] = s
+	}
+	return &validator{schemas: schemas, sources: sources}, nil
 }
 
-func (v *builder) addSchema(name string, schema map[string]interface{}) error {
+func (v *builder) addSchema(name string, schema map[string]interface{}, anchors map[string]string, staged map[string]registeredSchema) error {
 	if defs, ok := schema["definitions"]; ok {
 		if defsMap, ok := defs.(map[string]interface{}); !ok {
 			return errors.New("expected 'definitions' key of schema to be an object")
@@ -157,7 +258,7 @@ func (v *builder) addSchema(name string, schema map[string]interface{}) error {
 			for defKey, def := range defsMap {
 				if defMap, ok := def.(map[string]interface{}); !ok {
 					return fmt.Errorf("expected definition for '%s' to be an object", defKey)
-				} else if err := v.addSchema(defKey, defMap); err != nil {
+				} else if err := v.addSchema(defKey, defMap, anchors, staged); err != nil {
 					return errors.WithMessage(err, "failed to add schema with name: "+defKey)
 				}
 			}
@@ -165,17 +266,111 @@ func (v *builder) addSchema(name string, schema map[string]interface{}) error {
 	}
 	delete(schema, "definitions")
 	b, _ := json.Marshal(schema)
+	// references to anchors are replaced so that the schema does not depend on the document it was defined in.
+	b = anchorRefRegex.ReplaceAllFunc(b, func(match []byte) []byte {
+		if target, ok := anchors[string(anchorRefRegex.FindSubmatch(match)[1])]; ok {
+			return []byte(fmt.Sprintf(`"$ref":"%s"`, anchorRef(target)))
+		}
+		return match
+	})
 	items := refRegex.FindAllSubmatch(b, -1)
 	refs := make(map[string]struct{}, len(items))
 	for _, i := range items {
 		refs[string(i[1])] = struct{}{}
 	}
+	for _, i := range anchorRefRegex.FindAllSubmatch(b, -1) {
+		// an anchor which is never declared can not be a schema name, so it is reported as a missing reference.
+		refs["#"+string(i[1])] = struct{}{}
+	}
 	if _, ok := v.schemas[name]; ok {
 		return errors.New("multiple definitions for schema with name: " + name)
 	}
-	v.schemas[name] = registeredSchema{
+	if _, ok := staged[name]; ok {
+		return errors.New("multiple definitions for schema with name: " + name)
+	}
+	staged[name] = registeredSchema{
 		source:             b,
 		requiredReferences: refs,
+		deprecated:         schema["deprecated"] == true,
+		parametric:         hasVars(schema),
+	}
+	return nil
+}
+
+// Records the location of every '$anchor' within the schema as the name of the schema it will be added under,
+// followed by a json pointer to the anchor within that schema.
+// Definitions at the root of a schema are added under their own names, so anchors within them are recorded relative to those.
+// Returns an error if multiple locations declare the same anchor.
+func collectAnchors(name string, value interface{}, pointer string, root bool, anchors map[string]string) error {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for k, x := range v {
+			if k == "definitions" && root {
+				if defs, ok := x.(map[string]interface{}); ok {
+					for defKey, def := range defs {
+						if err := collectAnchors(defKey, def, "", true, anchors); err != nil {
+							return err
+						}
+					}
+				}
+			} else if anchor, ok := x.(string); ok && k == "$anchor" {
+				if _, ok := anchors[anchor]; ok {
+					return errors.New("multiple definitions for anchor with name: " + anchor)
+				}
+				anchors[anchor] = name + "#" + pointer
+			} else if err := collectAnchors(name, x, pointer+"/"+escapePointer(k), false, anchors); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for i, x := range v {
+			if err := collectAnchors(name, x, pointer+"/"+strconv.Itoa(i), false, anchors); err != nil {
+				return err
+			}
+		}
 	}
 	return nil
 }
+
+// Converts the location of an anchor, as recorded by collectAnchors, into a compliant reference.
+// The reference is followed by the json pointer to the anchor where it is not the root of the schema, such as "{Name}#/properties/x".
+func anchorRef(target string) string {
+	parts := strings.SplitN(target, "#", 2)
+	if parts[1] == "" {
+		return "{" + parts[0] + "}"
+	}
+	return "{" + parts[0] + "}#" + parts[1]
+}
+
+// The source of the schema as it is given to gojsonschema.
+// Compliant references are replaced with the canonical references used by the loader.
+func (s registeredSchema) compiledSource() []byte {
+	return SchemaRefReplace(s.source, refNameConvert)
+}
+
+// Returns the names of the schemas which contain variable placeholders, or which reference such schemas, directly or indirectly.
+// Schemas are marked repeatedly until no more are found, so that every schema in a cycle of references is marked.
+func requiresVars(schemas map[string]registeredSchema) map[string]bool {
+	out := make(map[string]bool, len(schemas))
+	for name, s := range schemas {
+		if s.parametric {
+			out[name] = true
+		}
+	}
+	for changed := true; changed; {
+		changed = false
+		for name, s := range schemas {
+			if out[name] {
+				continue
+			}
+			for n := range s.requiredReferences {
+				if out[n] {
+					out[name] = true
+					changed = true
+					break
+				}
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,78 @@
+package vjsonschema
+
+import (
+	"github.com/xeipuuv/gojsonschema"
+	"sync"
+)
+
+// A Validator which records the number of times that each schema has been validated against.
+// Useful for asserting that a test suite exercises every registered schema.
+type CoverageValidator struct {
+	Validator
+
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+// Wrap the validator in order to record which of its schemas are validated against.
+func NewCoverageValidator(v Validator) *CoverageValidator {
+	names := v.SchemaNames()
+	counts := make(map[string]int, len(names))
+	for _, name := range names {
+		counts[name] = 0
+	}
+	return &CoverageValidator{
+		Validator: v,
+		counts:    counts,
+	}
+}
+
+func (c *CoverageValidator) Validate(schemaName string, instance []byte) (*gojsonschema.Result, error) {
+	c.record(schemaName)
+	return c.Validator.Validate(schemaName, instance)
+}
+
+func (c *CoverageValidator) ValidateWithWarnings(schemaName string, instance []byte) (errs []ValidationError, warnings []ValidationError, valid bool, err error) {
+	c.record(schemaName)
+	return c.Validator.ValidateWithWarnings(schemaName, instance)
+}
+
+func (c *CoverageValidator) ValidateWithVars(schemaName string, instance []byte, vars map[string]interface{}) (*gojsonschema.Result, error) {
+	c.record(schemaName)
+	return c.Validator.ValidateWithVars(schemaName, instance, vars)
+}
+
+func (c *CoverageValidator) ValidateNullability(schemaName string, instance []byte) ([]ValidationError, error) {
+	c.record(schemaName)
+	return c.Validator.ValidateNullability(schemaName, instance)
+}
+
+func (c *CoverageValidator) ValidateUpdate(schemaName string, existing, incoming []byte) (*gojsonschema.Result, error) {
+	c.record(schemaName)
+	return c.Validator.ValidateUpdate(schemaName, existing, incoming)
+}
+
+func (c *CoverageValidator) ValidateParam(schemaName string, rawValue string, style string, explode bool) (*gojsonschema.Result, error) {
+	c.record(schemaName)
+	return c.Validator.ValidateParam(schemaName, rawValue, style, explode)
+}
+
+// Return a mapping of every schema name to the number of times it has been validated against.
+// Schemas which have never been validated against are included with a count of zero.
+func (c *CoverageValidator) Coverage() map[string]int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make(map[string]int, len(c.counts))
+	for name, n := range c.counts {
+		out[name] = n
+	}
+	return out
+}
+
+func (c *CoverageValidator) record(schemaName string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.counts[schemaName]; ok {
+		c.counts[schemaName]++
+	}
+}
@@ -0,0 +1,332 @@
+package vjsonschema
+
+import (
+	"encoding/json"
+	"github.com/pkg/errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Sample values for strings with a known 'format'.
+var exampleFormats = map[string]string{
+	"date-time": "1970-01-01T00:00:00Z",
+	"date":      "1970-01-01",
+	"time":      "00:00:00Z",
+	"email":     "user@example.com",
+	"hostname":  "example.com",
+	"ipv4":      "127.0.0.1",
+	"ipv6":      "::1",
+	"uri":       "https://example.com",
+	"uuid":      "00000000-0000-0000-0000-000000000000",
+}
+
+func (v *builder) GenerateExample(name string) ([]byte, error) {
+	schema, err := parseSchema(v.schemas, name)
+	if err != nil {
+		return nil, err
+	}
+	value, err := exampleValue(v.schemas, schema, map[string]struct{}{name: {}})
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to generate example for schema with name: "+name)
+	}
+	return json.Marshal(value)
+}
+
+// Produces a minimal value which is valid against the schema.
+// 'refs' holds the names of the schemas currently being generated, which is used to detect required circular references.
+func exampleValue(schemas map[string]registeredSchema, schema map[string]interface{}, refs map[string]struct{}) (interface{}, error) {
+	if ref, ok := compliantRef(schema); ok {
+		if _, ok := refs[ref]; ok {
+			return nil, errors.New("cannot generate an example through a required circular reference to: " + ref)
+		}
+		s, err := resolveRef(schemas, ref)
+		if err != nil {
+			return nil, err
+		}
+		refs[ref] = struct{}{}
+		value, err := exampleValue(schemas, s, refs)
+		delete(refs, ref)
+		return value, err
+	}
+
+	if c, ok := schema["const"]; ok {
+		return c, nil
+	}
+	if d, ok := schema["default"]; ok {
+		return d, nil
+	}
+	if e, ok := schema["examples"].([]interface{}); ok && len(e) > 0 {
+		return e[0], nil
+	}
+	if e, ok := schema["example"]; ok {
+		return e, nil
+	}
+	if e, ok := schema["enum"].([]interface{}); ok && len(e) > 0 {
+		return e[0], nil
+	}
+
+	for _, kw := range []string{"oneOf", "anyOf"} {
+		if subs, ok := schema[kw].([]interface{}); ok && len(subs) > 0 {
+			if sub, ok := subs[0].(map[string]interface{}); ok {
+				return exampleValue(schemas, sub, refs)
+			}
+		}
+	}
+	if subs, ok := schema["allOf"].([]interface{}); ok {
+		return exampleAllOf(schemas, schema, subs, refs)
+	}
+
+	switch primaryType(schema) {
+	case "boolean":
+		return false, nil
+	case "integer":
+		return exampleNumber(schema, true)
+	case "number":
+		return exampleNumber(schema, false)
+	case "string":
+		return exampleString(schema)
+	case "array":
+		return exampleArray(schemas, schema, refs)
+	case "object":
+		return exampleObject(schemas, schema, refs)
+	}
+	return nil, nil
+}
+
+// Merges the examples of each of the 'allOf' schemas, along with the example of the schema itself.
+func exampleAllOf(schemas map[string]registeredSchema, schema map[string]interface{}, subs []interface{}, refs map[string]struct{}) (interface{}, error) {
+	rest := make(map[string]interface{}, len(schema))
+	for k, x := range schema {
+		if k != "allOf" {
+			rest[k] = x
+		}
+	}
+	merged := make(map[string]interface{})
+	var first interface{}
+	for i, sub := range append(subs, rest) {
+		s, ok := sub.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if i == len(subs) && primaryType(s) == "null" {
+			break
+		}
+		value, err := exampleValue(schemas, s, refs)
+		if err != nil {
+			return nil, err
+		}
+		if m, ok := value.(map[string]interface{}); ok {
+			for k, x := range m {
+				merged[k] = x
+			}
+		} else if first == nil {
+			first = value
+		}
+	}
+	if len(merged) == 0 && first != nil {
+		return first, nil
+	}
+	return merged, nil
+}
+
+// Generates the number closest to zero which satisfies the bounds and 'multipleOf' of the schema.
+func exampleNumber(schema map[string]interface{}, integer bool) (float64, error) {
+	n := 0.0
+	if min, ok := schema["minimum"].(float64); ok && n < min {
+		n = min
+		if integer {
+			n = math.Ceil(n)
+		}
+	}
+	if min, ok := schema["exclusiveMinimum"].(float64); ok && n <= min {
+		n = math.Floor(min) + 1
+	}
+	multiple, _ := schema["multipleOf"].(float64)
+	if multiple > 0 {
+		n = math.Ceil(n/multiple) * multiple
+		if min, ok := schema["exclusiveMinimum"].(float64); ok && n <= min {
+			n += multiple
+		}
+		for i := 0; integer && n != math.Trunc(n) && i < 100; i++ {
+			n += multiple
+		}
+	}
+	if !belowMaximum(schema, n) {
+		if multiple > 0 {
+			return 0, errors.New("no multiple of 'multipleOf' is within the bounds of the schema")
+		}
+		if max, ok := schema["maximum"].(float64); ok && n > max {
+			n = max
+			if integer {
+				n = math.Floor(n)
+			}
+		}
+		if max, ok := schema["exclusiveMaximum"].(float64); ok && n >= max {
+			n = math.Ceil(max) - 1
+		}
+	}
+	return n, nil
+}
+
+// Returns true if the number satisfies the 'maximum' and 'exclusiveMaximum' of the schema.
+func belowMaximum(schema map[string]interface{}, n float64) bool {
+	if max, ok := schema["maximum"].(float64); ok && n > max {
+		return false
+	}
+	if max, ok := schema["exclusiveMaximum"].(float64); ok && n >= max {
+		return false
+	}
+	return true
+}
+
+// Generates a string satisfying the 'minLength' and 'maxLength' of the schema,
+// using a sample value for strings with a known 'format'.
+func exampleString(schema map[string]interface{}) (string, error) {
+	s := "string"
+	format, _ := schema["format"].(string)
+	sample, isFormat := exampleFormats[format]
+	if isFormat {
+		s = sample
+	}
+	if min, ok := schema["minLength"].(float64); ok && len(s) < int(min) {
+		if isFormat {
+			return "", errors.New("the sample value for format '" + format + "' is shorter than 'minLength'")
+		}
+		s += strings.Repeat("x", int(min)-len(s))
+	}
+	if max, ok := schema["maxLength"].(float64); ok && len(s) > int(max) {
+		if isFormat {
+			return "", errors.New("the sample value for format '" + format + "' is longer than 'maxLength'")
+		}
+		s = s[:int(max)]
+	}
+	return s, nil
+}
+
+// Generates an array with a single item, or more if required by 'minItems'.
+// The items are distinct from one another if the schema has 'uniqueItems'.
+func exampleArray(schemas map[string]registeredSchema, schema map[string]interface{}, refs map[string]struct{}) (interface{}, error) {
+	out := make([]interface{}, 0, 1)
+	switch items := schema["items"].(type) {
+	case map[string]interface{}:
+		value, err := exampleValue(schemas, items, refs)
+		if err != nil {
+			return nil, err
+		}
+		n := 1
+		if min, ok := schema["minItems"].(float64); ok && int(min) > n {
+			n = int(min)
+		}
+		if n > 1 && schema["uniqueItems"] == true {
+			out, err = exampleDistinct(derefSchema(schemas, items), value, n)
+			if err != nil {
+				return nil, errors.WithMessage(err, "keyword 'uniqueItems'")
+			}
+			return out, nil
+		}
+		for i := 0; i < n; i++ {
+			out = append(out, value)
+		}
+	case []interface{}:
+		for _, item := range items {
+			s, ok := item.(map[string]interface{})
+			if !ok {
+				break
+			}
+			value, err := exampleValue(schemas, s, refs)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, value)
+		}
+	}
+	return out, nil
+}
+
+// Generates 'n' distinct values for the items schema, starting with 'first'.
+// Only enums, booleans, numbers and strings without a 'format' are supported.
+func exampleDistinct(items map[string]interface{}, first interface{}, n int) ([]interface{}, error) {
+	if e, ok := items["enum"].([]interface{}); ok {
+		if len(e) < n {
+			return nil, errors.New("the enum of the items has fewer values than 'minItems'")
+		}
+		return e[:n], nil
+	}
+	if _, ok := items["const"]; ok {
+		return nil, errors.New("cannot generate distinct items for a const")
+	}
+	out := make([]interface{}, 0, n)
+	switch primaryType(items) {
+	case "boolean":
+		if n > 2 {
+			return nil, errors.New("cannot generate more than 2 distinct booleans")
+		}
+		return append(out, false, true)[:n], nil
+	case "integer", "number":
+		step, _ := items["multipleOf"].(float64)
+		if step <= 0 {
+			step = 1
+		}
+		x, ok := first.(float64)
+		if !ok {
+			return nil, errors.New("cannot generate distinct items from a non-numeric example")
+		}
+		for i := 0; i < n; i++ {
+			if !belowMaximum(items, x) {
+				return nil, errors.New("not enough distinct numbers are within the bounds of the items")
+			}
+			out = append(out, x)
+			x += step
+		}
+		return out, nil
+	case "string":
+		base, ok := first.(string)
+		format, _ := items["format"].(string)
+		if _, isFormat := exampleFormats[format]; !ok || isFormat {
+			return nil, errors.New("cannot generate distinct strings for the items")
+		}
+		out = append(out, base)
+		for i := 1; i < n; i++ {
+			suffix := strconv.Itoa(i)
+			s := base
+			if max, ok := items["maxLength"].(float64); ok && len(s)+len(suffix) > int(max) {
+				if len(suffix) > int(max) {
+					return nil, errors.New("not enough distinct strings are within the 'maxLength' of the items")
+				}
+				s = s[:int(max)-len(suffix)]
+			}
+			out = append(out, s+suffix)
+		}
+		return out, nil
+	}
+	return nil, errors.New("cannot generate distinct items of type " + primaryType(items))
+}
+
+// Generates an object containing only the required properties.
+func exampleObject(schemas map[string]registeredSchema, schema map[string]interface{}, refs map[string]struct{}) (interface{}, error) {
+	out := make(map[string]interface{})
+	required, _ := schema["required"].([]interface{})
+	props, _ := schema["properties"].(map[string]interface{})
+	additional, _ := schema["additionalProperties"].(map[string]interface{})
+	for _, r := range required {
+		name, ok := r.(string)
+		if !ok {
+			continue
+		}
+		s, ok := props[name].(map[string]interface{})
+		if !ok {
+			s = additional
+		}
+		if s == nil {
+			out[name] = nil
+			continue
+		}
+		value, err := exampleValue(schemas, s, refs)
+		if err != nil {
+			return nil, errors.WithMessage(err, "property '"+name+"'")
+		}
+		out[name] = value
+	}
+	return out, nil
+}
@@ -0,0 +1,214 @@
+package vjsonschema
+
+import (
+	"encoding/json"
+	"github.com/pkg/errors"
+	"github.com/xeipuuv/gojsonschema"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	deepObjectKeyRegex = regexp.MustCompile(`^([^\[]*)\[([^\]]*)\]$`)
+)
+
+func (v *validator) ValidateParam(schemaName string, rawValue string, style string, explode bool) (*gojsonschema.Result, error) {
+	schema, err := parseSchema(v.sources, schemaName)
+	if err != nil {
+		return nil, err
+	}
+	schema = derefSchema(v.sources, schema)
+	value, err := parseParam(v.sources, schema, rawValue, style, explode)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to parse parameter with style: "+style)
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to marshal parameter as json")
+	}
+	return v.Validate(schemaName, b)
+}
+
+// Parses the raw parameter into a value of the type described by the schema.
+func parseParam(schemas map[string]registeredSchema, schema map[string]interface{}, raw, style string, explode bool) (interface{}, error) {
+	t := primaryType(schema)
+	switch style {
+	case "form":
+		if explode {
+			return parseExploded(schemas, schema, t, raw)
+		}
+		return parseDelimited(schemas, schema, t, raw, ",", "")
+	case "spaceDelimited":
+		if explode {
+			return parseExploded(schemas, schema, t, raw)
+		}
+		return parseDelimited(schemas, schema, t, raw, " ", "%20")
+	case "pipeDelimited":
+		if explode {
+			return parseExploded(schemas, schema, t, raw)
+		}
+		return parseDelimited(schemas, schema, t, raw, "|", "")
+	case "deepObject":
+		if t != "object" {
+			return nil, errors.New("style 'deepObject' may only be used with objects")
+		}
+		pairs, err := parsePairs(raw)
+		if err != nil {
+			return nil, err
+		}
+		out := make(map[string]interface{}, len(pairs))
+		name := ""
+		for i, p := range pairs {
+			m := deepObjectKeyRegex.FindStringSubmatch(p[0])
+			if m == nil {
+				return nil, errors.New("expected key of the form name[property], found: " + p[0])
+			}
+			if i == 0 {
+				name = m[1]
+			} else if m[1] != name {
+				return nil, errors.New("expected the pairs of a single parameter, found: " + name + " and " + m[1])
+			}
+			out[m[2]] = coerceParam(schemas, propertySchema(schema, m[2]), p[1])
+		}
+		return out, nil
+	default:
+		return nil, errors.New("unsupported style - must be one of {form, spaceDelimited, pipeDelimited, deepObject}")
+	}
+}
+
+// Parses a parameter whose values are separated by the delimiter, such as 'a,b,c' for arrays or 'k1,v1,k2,v2' for objects.
+// Where the delimiter must itself be percent-encoded, such as the space of spaceDelimited, 'encoded' is also accepted as the delimiter.
+// Otherwise, a percent-encoded delimiter is part of a value, as in 'a%2Cb,c'.
+func parseDelimited(schemas map[string]registeredSchema, schema map[string]interface{}, t, raw, delim, encoded string) (interface{}, error) {
+	if t != "array" && t != "object" {
+		s, err := url.QueryUnescape(raw)
+		if err != nil {
+			return nil, err
+		}
+		return coerceParam(schemas, schema, s), nil
+	}
+	parts := make([]string, 0)
+	if raw != "" {
+		if encoded != "" {
+			raw = strings.Replace(raw, encoded, delim, -1)
+		}
+		parts = strings.Split(raw, delim)
+	}
+	for i, p := range parts {
+		s, err := url.QueryUnescape(p)
+		if err != nil {
+			return nil, err
+		}
+		parts[i] = s
+	}
+	if t == "array" {
+		items, _ := schema["items"].(map[string]interface{})
+		out := make([]interface{}, len(parts))
+		for i, p := range parts {
+			out[i] = coerceParam(schemas, items, p)
+		}
+		return out, nil
+	}
+	if len(parts)%2 != 0 {
+		return nil, errors.New("expected object to be a list of alternating keys and values")
+	}
+	out := make(map[string]interface{}, len(parts)/2)
+	for i := 0; i < len(parts); i += 2 {
+		out[parts[i]] = coerceParam(schemas, propertySchema(schema, parts[i]), parts[i+1])
+	}
+	return out, nil
+}
+
+// Parses an exploded parameter, given as the pairs of a query string such as 'id=1&id=2'.
+// Arrays collect the value of every pair, while objects use each pair as a property.
+func parseExploded(schemas map[string]registeredSchema, schema map[string]interface{}, t, raw string) (interface{}, error) {
+	if !strings.Contains(raw, "=") && t != "array" && t != "object" {
+		return parseDelimited(schemas, schema, t, raw, "&", "")
+	}
+	pairs, err := parsePairs(raw)
+	if err != nil {
+		return nil, err
+	}
+	switch t {
+	case "array":
+		items, _ := schema["items"].(map[string]interface{})
+		out := make([]interface{}, len(pairs))
+		for i, p := range pairs {
+			if p[0] != pairs[0][0] {
+				return nil, errors.New("expected the pairs of a single parameter, found: " + pairs[0][0] + " and " + p[0])
+			}
+			out[i] = coerceParam(schemas, items, p[1])
+		}
+		return out, nil
+	case "object":
+		out := make(map[string]interface{}, len(pairs))
+		for _, p := range pairs {
+			out[p[0]] = coerceParam(schemas, propertySchema(schema, p[0]), p[1])
+		}
+		return out, nil
+	default:
+		if len(pairs) != 1 {
+			return nil, errors.New("expected a single value")
+		}
+		return coerceParam(schemas, schema, pairs[0][1]), nil
+	}
+}
+
+// Splits a query string into its unescaped key and value pairs, retaining their order.
+func parsePairs(raw string) ([][2]string, error) {
+	pairs := make([][2]string, 0)
+	for _, p := range strings.Split(raw, "&") {
+		if p == "" {
+			continue
+		}
+		kv := strings.SplitN(p, "=", 2)
+		if len(kv) != 2 {
+			return nil, errors.New("expected a pair of the form key=value, found: " + p)
+		}
+		k, err := url.QueryUnescape(kv[0])
+		if err != nil {
+			return nil, err
+		}
+		v, err := url.QueryUnescape(kv[1])
+		if err != nil {
+			return nil, err
+		}
+		pairs = append(pairs, [2]string{k, v})
+	}
+	return pairs, nil
+}
+
+// Returns the schema of the property of an object, falling back to 'additionalProperties'.
+func propertySchema(schema map[string]interface{}, name string) map[string]interface{} {
+	props, _ := schema["properties"].(map[string]interface{})
+	if s, ok := props[name].(map[string]interface{}); ok {
+		return s
+	}
+	s, _ := schema["additionalProperties"].(map[string]interface{})
+	return s
+}
+
+// Converts the string into the type described by the schema.
+// Strings which cannot be converted are left as strings so that validation reports the type mismatch.
+func coerceParam(schemas map[string]registeredSchema, schema map[string]interface{}, s string) interface{} {
+	if schema == nil {
+		return s
+	}
+	switch primaryType(derefSchema(schemas, schema)) {
+	case "integer", "number":
+		if n, err := strconv.ParseFloat(s, 64); err == nil {
+			return n
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b
+		}
+	case "null":
+		if s == "" || s == "null" {
+			return nil
+		}
+	}
+	return s
+}
@@ -1,8 +1,12 @@
 package test
 
 import (
+	"bytes"
+	"encoding/json"
 	"github.com/tjbrockmeyer/vjsonschema"
+	"github.com/xeipuuv/gojsonschema"
 	"io/ioutil"
+	"sort"
 	"strings"
 	"testing"
 )
@@ -55,6 +59,7 @@ func TestValidator(t *testing.T) {
 	t.Run("simple", testSchema("Simple", "Simple", "Abc"))
 	t.Run("hasRefs", testSchema("HasRefs", "HasRefs", "One"))
 	t.Run("circular", testSchema("Circular", "Circular", "Circular"))
+	t.Run("anchors", testSchema("Anchors", "Anchors", "Anchors"))
 	t.Run("multiple file refs", func(t *testing.T) {
 		factory := vjsonschema.NewBuilder()
 		if err := factory.AddFile("./schemas/F1.json"); err != nil {
@@ -88,6 +93,46 @@ func TestValidator(t *testing.T) {
 			return
 		}
 	})
+	t.Run("raw message", func(t *testing.T) {
+		fac := vjsonschema.NewBuilder()
+		if err := fac.AddSchema("Raw", json.RawMessage(`{"type":"string","maxLength":3}`)); err != nil {
+			t.Fatal(err)
+		}
+		v, err := fac.Compile()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r, err := v.Validate("Raw", []byte(`"abcd"`)); err != nil {
+			t.Error(err)
+		} else if r.Valid() {
+			t.Error("expected the schema given as a json.RawMessage to be enforced")
+		}
+	})
+	t.Run("missing anchors", func(t *testing.T) {
+		fac := vjsonschema.NewBuilder()
+		if err := fac.AddFile("./schemas/MissingAnchor.json"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fac.GenerateExample("MissingAnchor"); err == nil || !strings.Contains(err.Error(), "adress") {
+			t.Error("expected an error about the missing anchor, found:", err)
+		}
+		_, err := fac.Compile()
+		if err == nil {
+			t.Error("expected an error, found none")
+		} else if !strings.Contains(err.Error(), "missing required references: #adress(MissingAnchor)") {
+			t.Error("found error, but it should be about the missing anchor:", err)
+		}
+	})
+	t.Run("duplicate anchors", func(t *testing.T) {
+		fac := vjsonschema.NewBuilder()
+		err := fac.AddSchema("Dup", []byte(`{"properties":{"a":{"$anchor":"x"}},"definitions":{"B":{"$anchor":"x"}}}`))
+		if err == nil || !strings.Contains(err.Error(), "anchor") {
+			t.Error("expected an error about the duplicate anchor, found:", err)
+		}
+		if len(fac.GetSchemas()) != 0 {
+			t.Error("expected no schemas to be added")
+		}
+	})
 	t.Run("missing refs", func(t *testing.T) {
 		fac := vjsonschema.NewBuilder()
 		if err := fac.AddFile("./schemas/MissingRefs.json"); err != nil {
@@ -101,3 +146,649 @@ func TestValidator(t *testing.T) {
 		}
 	})
 }
+
+func TestValidateWithWarnings(t *testing.T) {
+	factory := vjsonschema.NewBuilder()
+	if err := factory.AddFile("./schemas/Deprecated.json"); err != nil {
+		t.Fatal(err)
+	}
+	v, err := factory.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	errs, warnings, valid, err := v.ValidateWithWarnings("Deprecated", readFile("./payloads/DeprecatedPass.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !valid || len(errs) != 0 {
+		t.Error("expected instance to be valid, found errors:", errs)
+	}
+	fields := make([]string, 0, len(warnings))
+	for _, w := range warnings {
+		if w.Type != "deprecated" {
+			t.Error("expected warning to be of type 'deprecated':", w)
+		}
+		fields = append(fields, w.Field)
+	}
+	if strings.Join(fields, ",") != "alias.old,nickname" {
+		t.Error("expected warnings for alias.old and nickname, found:", fields)
+	}
+
+	errs, warnings, valid, err = v.ValidateWithWarnings("Deprecated", []byte(`{"nickname":1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if valid || len(errs) == 0 {
+		t.Error("expected instance to be invalid")
+	}
+	if len(warnings) != 1 {
+		t.Error("expected a single warning, found:", warnings)
+	}
+}
+
+func TestDeprecatedSchemas(t *testing.T) {
+	factory := vjsonschema.NewBuilder()
+	if err := factory.AddFile("./schemas/Simple.json"); err != nil {
+		t.Fatal(err)
+	}
+	if err := factory.AddSchema("Old", []byte(`{"type":"string","deprecated":true,"definitions":{"OlderStill":{"deprecated":true}}}`)); err != nil {
+		t.Fatal(err)
+	}
+	if d := factory.DeprecatedSchemas(); strings.Join(d, ",") != "Old,OlderStill" {
+		t.Error("expected Old and OlderStill to be deprecated, found:", d)
+	}
+	v, err := factory.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !v.IsDeprecated("Old") || !v.IsDeprecated("OlderStill") {
+		t.Error("expected Old and OlderStill to be deprecated")
+	}
+	if v.IsDeprecated("Simple") || v.IsDeprecated("Abc") || v.IsDeprecated("DoesNotExist") {
+		t.Error("expected Simple, Abc and DoesNotExist to not be deprecated")
+	}
+}
+
+func TestEffectiveSchema(t *testing.T) {
+	factory := vjsonschema.NewBuilder()
+	if err := factory.AddFile("./schemas/HasRefs.json"); err != nil {
+		t.Fatal(err)
+	}
+	if err := factory.AddFile("./schemas/Circular.json"); err != nil {
+		t.Fatal(err)
+	}
+	if err := factory.AddFile("./schemas/Anchors.json"); err != nil {
+		t.Fatal(err)
+	}
+	v, err := factory.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("inlined", func(t *testing.T) {
+		effective, err := v.EffectiveSchema("HasRefs")
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := `{"properties":{"abc":{"enum":["x","y","z"],"type":"string"}},"type":"object"}`
+		if string(effective) != expected {
+			t.Errorf("expected %s, found %s", expected, effective)
+		}
+	})
+	t.Run("circular", func(t *testing.T) {
+		effective, err := v.EffectiveSchema("Circular")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(effective), `"next":{"$ref":"#"}`) {
+			t.Error("expected the circular reference to point to the root, found:", string(effective))
+		}
+		schema, err := gojsonschema.NewSchema(gojsonschema.NewBytesLoader(effective))
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := schema.Validate(gojsonschema.NewBytesLoader(readFile("./payloads/CircularFail.json")))
+		if err != nil {
+			t.Fatal(err)
+		} else if r.Valid() {
+			t.Error("expected the effective schema to enforce the circular reference")
+		}
+	})
+	t.Run("anchors", func(t *testing.T) {
+		effective, err := v.EffectiveSchema("Anchors")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(effective), "$ref") || strings.Contains(string(effective), "$anchor") {
+			t.Error("expected every reference to an anchor to be inlined, found:", string(effective))
+		}
+		if !strings.Contains(string(effective), `"code":{"pattern":"^[0-9]{5}$","type":"string"}`) {
+			t.Error("expected the reference to a nested anchor to be inlined, found:", string(effective))
+		}
+		schema, err := gojsonschema.NewSchema(gojsonschema.NewBytesLoader(effective))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for payload, valid := range map[string]bool{"./payloads/AnchorsPass.json": true, "./payloads/AnchorsFail.json": false} {
+			if r, err := schema.Validate(gojsonschema.NewBytesLoader(readFile(payload))); err != nil {
+				t.Error(err)
+			} else if r.Valid() != valid {
+				t.Errorf("expected validity of %s against the effective schema to be %v", payload, valid)
+			}
+		}
+	})
+	t.Run("missing", func(t *testing.T) {
+		if _, err := v.EffectiveSchema("DoesNotExist"); err == nil {
+			t.Error("expected an error for a schema which does not exist")
+		}
+	})
+}
+
+func TestGenerateExample(t *testing.T) {
+	factory := vjsonschema.NewBuilder()
+	if err := factory.AddFile("./schemas/F1.json"); err != nil {
+		t.Fatal(err)
+	}
+	if err := factory.AddFile("./schemas/F2.json"); err != nil {
+		t.Fatal(err)
+	}
+	if err := factory.AddFile("./schemas/Circular.json"); err != nil {
+		t.Fatal(err)
+	}
+	if err := factory.AddSchema("Everything", []byte(`{
+		"type": "object",
+		"required": ["id", "created", "tags", "count", "name", "kind", "nested"],
+		"properties": {
+			"id": {"type": "string", "format": "uuid"},
+			"created": {"type": "string", "format": "date-time"},
+			"tags": {"type": "array", "items": {"type": "string", "minLength": 10}, "minItems": 2},
+			"count": {"type": ["null", "integer"], "minimum": 3.5, "maximum": 10},
+			"name": {"type": "string", "default": "abc"},
+			"kind": {"const": "thing"},
+			"nested": {"allOf": [{"$ref": "{F1}"}, {"type": "object", "required": ["flag"], "properties": {"flag": {"type": "boolean"}}}]},
+			"optional": {"type": "string"}
+		}
+	}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := factory.AddFile("./schemas/Anchors.json"); err != nil {
+		t.Fatal(err)
+	}
+	if err := factory.AddSchema("Constrained", []byte(`{
+		"type": "object",
+		"required": ["a", "b", "c", "d", "e", "f", "g"],
+		"properties": {
+			"a": {"type": "integer", "minimum": 1, "multipleOf": 5},
+			"b": {"type": "array", "items": {"type": "integer"}, "minItems": 2, "uniqueItems": true},
+			"c": {"type": "string", "format": "email", "maxLength": 20},
+			"d": {"type": "array", "items": {"type": "string", "maxLength": 6}, "minItems": 3, "uniqueItems": true},
+			"e": {"type": "number", "exclusiveMinimum": 0, "multipleOf": 0.5},
+			"f": {"type": "array", "items": {"enum": ["x", "y", "z"]}, "minItems": 2, "uniqueItems": true},
+			"g": {"type": "array", "items": {"type": "boolean"}, "minItems": 2, "uniqueItems": true}
+		}
+	}`)); err != nil {
+		t.Fatal(err)
+	}
+	impossible := map[string]string{
+		"ShortEmail":      `{"type":"string","format":"email","maxLength":5}`,
+		"UniqueConst":     `{"type":"array","items":{"const":1},"minItems":2,"uniqueItems":true}`,
+		"UniqueObjects":   `{"type":"array","items":{"type":"object"},"minItems":2,"uniqueItems":true}`,
+		"NoMultiple":      `{"type":"integer","minimum":1,"maximum":4,"multipleOf":5}`,
+		"TooManyBooleans": `{"type":"array","items":{"type":"boolean"},"minItems":3,"uniqueItems":true}`,
+	}
+	for name, schema := range impossible {
+		if err := factory.AddSchema(name, []byte(schema)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	v, err := factory.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"F2", "Circular", "Everything", "Anchors", "Constrained"} {
+		example, err := factory.GenerateExample(name)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		t.Log(name, string(example))
+		r, err := v.Validate(name, example)
+		if err != nil {
+			t.Error(err)
+		} else if !r.Valid() {
+			for _, err := range r.Errors() {
+				t.Error(name, err)
+			}
+		}
+	}
+	for name := range impossible {
+		if example, err := factory.GenerateExample(name); err == nil {
+			t.Errorf("expected an error generating an example for %s, found %s", name, example)
+		}
+	}
+	if _, err = factory.GenerateExample("DoesNotExist"); err == nil {
+		t.Error("expected an error for a schema which does not exist")
+	}
+}
+
+func TestCoverageValidator(t *testing.T) {
+	factory := vjsonschema.NewBuilder()
+	if err := factory.AddFile("./schemas/F1.json"); err != nil {
+		t.Fatal(err)
+	}
+	if err := factory.AddFile("./schemas/F2.json"); err != nil {
+		t.Fatal(err)
+	}
+	v, err := factory.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := v.SchemaNames()
+	if strings.Join(names, ",") != "Abc,ElmEnnoPi,F1,F2,OneTwoThree" {
+		t.Error("unexpected schema names:", names)
+	}
+	c := vjsonschema.NewCoverageValidator(v)
+	for _, instance := range []string{`"a"`, `"z"`} {
+		if _, err = c.Validate("Abc", []byte(instance)); err != nil {
+			t.Error(err)
+		}
+	}
+	if _, _, _, err = c.ValidateWithWarnings("ElmEnnoPi", []byte(`"l"`)); err != nil {
+		t.Error(err)
+	}
+	if _, err = c.Validate("DoesNotExist", []byte(`{}`)); err == nil {
+		t.Error("expected an error for a schema which does not exist")
+	}
+	coverage := c.Coverage()
+	expected := map[string]int{"Abc": 2, "ElmEnnoPi": 1, "F1": 0, "F2": 0, "OneTwoThree": 0}
+	if len(coverage) != len(expected) {
+		t.Error("expected coverage to contain exactly the registered schemas, found:", coverage)
+	}
+	for name, n := range expected {
+		if coverage[name] != n {
+			t.Errorf("expected coverage of %s to be %d, found %d", name, n, coverage[name])
+		}
+	}
+}
+
+func TestValidateWithVars(t *testing.T) {
+	factory := vjsonschema.NewBuilder()
+	if err := factory.AddFile("./schemas/Simple.json"); err != nil {
+		t.Fatal(err)
+	}
+	if err := factory.AddSchema("Name", []byte(`{"type":"string","maxLength":"${limit}"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := factory.AddSchema("Person", []byte(`{"type":"object","properties":{"name":{"$ref":"{Name}"}}}`)); err != nil {
+		t.Fatal(err)
+	}
+	v, err := factory.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		schema   string
+		instance string
+		limit    int
+		valid    bool
+	}{
+		{"Name", `"abcdef"`, 3, false},
+		{"Name", `"abcdef"`, 10, true},
+		{"Person", `{"name":"abcdef"}`, 3, false},
+		{"Person", `{"name":"abcdef"}`, 10, true},
+		{"Name", `"abcdef"`, 3, false},
+	}
+	for _, c := range cases {
+		r, err := v.ValidateWithVars(c.schema, []byte(c.instance), map[string]interface{}{"limit": c.limit})
+		if err != nil {
+			t.Error(err)
+		} else if r.Valid() != c.valid {
+			t.Errorf("expected validity of %s against %s with limit %d to be %v", c.instance, c.schema, c.limit, c.valid)
+		}
+	}
+	if _, err = v.Validate("Name", []byte(`"abc"`)); err == nil {
+		t.Error("expected an error when validating against a schema with vars using Validate")
+	}
+	if _, err = v.ValidateWithVars("Name", []byte(`"abc"`), nil); err == nil || !strings.Contains(err.Error(), "limit") {
+		t.Error("expected an error about the missing var, found:", err)
+	}
+	if r, err := v.ValidateWithVars("Simple", readFile("./payloads/SimplePass.json"), nil); err != nil || !r.Valid() {
+		t.Error("expected schemas without vars to be validated normally")
+	}
+}
+
+func TestValidateWithVarsCycle(t *testing.T) {
+	// run repeatedly, as the order in which schemas are visited is random.
+	for i := 0; i < 50; i++ {
+		factory := vjsonschema.NewBuilder()
+		if err := factory.AddSchema("X", []byte(`{"type":"object","properties":{"y":{"$ref":"{Y}"},"p":{"$ref":"{P}"}}}`)); err != nil {
+			t.Fatal(err)
+		}
+		if err := factory.AddSchema("Y", []byte(`{"type":"object","properties":{"x":{"$ref":"{X}"}}}`)); err != nil {
+			t.Fatal(err)
+		}
+		if err := factory.AddSchema("P", []byte(`{"type":"string","maxLength":"${limit}"}`)); err != nil {
+			t.Fatal(err)
+		}
+		v, err := factory.Compile()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range []string{"X", "Y"} {
+			if _, err = v.Validate(name, []byte(`{}`)); err == nil || !strings.Contains(err.Error(), "requires variables") {
+				t.Fatal("expected every schema in the cycle to require vars, found:", err)
+			}
+		}
+	}
+}
+
+func TestValidateWithVarsUnrelated(t *testing.T) {
+	factory := vjsonschema.NewBuilder()
+	if err := factory.AddSchema("A", []byte(`{"type":"string","maxLength":"${limit}"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := factory.AddSchema("B", []byte(`{"type":"string","minLength":"${other}"}`)); err != nil {
+		t.Fatal(err)
+	}
+	v, err := factory.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, err := v.ValidateWithVars("A", []byte(`"abcdef"`), map[string]interface{}{"limit": 5}); err != nil {
+		t.Error("expected vars of unreferenced schemas not to be required:", err)
+	} else if r.Valid() {
+		t.Error("expected instance to be invalid against A with a limit of 5")
+	}
+}
+
+func TestLiteralPlaceholders(t *testing.T) {
+	factory := vjsonschema.NewBuilder()
+	if err := factory.AddSchema("Home", []byte(`{"type":"string","default":"${HOME}","examples":["${HOME}"]}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := factory.AddSchema("Const", []byte(`{"const":"${x}"}`)); err != nil {
+		t.Fatal(err)
+	}
+	v, err := factory.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, err := v.Validate("Home", []byte(`"/root"`)); err != nil || !r.Valid() {
+		t.Error("expected schema with a literal placeholder default to be validated normally:", err)
+	}
+	if r, err := v.Validate("Const", []byte(`"${x}"`)); err != nil || !r.Valid() {
+		t.Error("expected schema with a literal placeholder const to be validated normally:", err)
+	}
+}
+
+func TestValidateNullability(t *testing.T) {
+	factory := vjsonschema.NewBuilder()
+	if err := factory.AddFile("./schemas/Nullable.json"); err != nil {
+		t.Fatal(err)
+	}
+	v, err := factory.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("null", func(t *testing.T) {
+		instance := []byte(`{"name":null,"middleName":null,"age":null,"tags":["a",null],"choice":null}`)
+		errs, err := v.ValidateNullability("Nullable", instance)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fields := make([]string, 0, len(errs))
+		for _, e := range errs {
+			if e.Type != "null_not_allowed" {
+				t.Error("expected error to be of type 'null_not_allowed':", e)
+			}
+			fields = append(fields, e.Field)
+		}
+		if strings.Join(fields, ",") != "age,name,tags.1" {
+			t.Error("expected errors for age, name and tags.1, found:", fields)
+		}
+	})
+	t.Run("missing", func(t *testing.T) {
+		instance := []byte(`{"middleName":"x"}`)
+		errs, err := v.ValidateNullability("Nullable", instance)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(errs) != 0 {
+			t.Error("expected no nullability errors for a missing field, found:", errs)
+		}
+		r, err := v.Validate("Nullable", instance)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(r.Errors()) != 1 || r.Errors()[0].Type() != "required" || r.Errors()[0].Field() != "(root)" {
+			t.Error("expected a single required error for the missing field, found:", r.Errors())
+		}
+	})
+	t.Run("anchors", func(t *testing.T) {
+		factory := vjsonschema.NewBuilder()
+		if err := factory.AddFile("./schemas/Anchors.json"); err != nil {
+			t.Fatal(err)
+		}
+		v, err := factory.Compile()
+		if err != nil {
+			t.Fatal(err)
+		}
+		errs, err := v.ValidateNullability("Anchors", []byte(`{"home":{"street":null},"alias":null}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		fields := make([]string, 0, len(errs))
+		for _, e := range errs {
+			fields = append(fields, e.Field)
+		}
+		if strings.Join(fields, ",") != "alias,home.street" {
+			t.Error("expected errors for alias and home.street, found:", fields)
+		}
+	})
+}
+
+func TestValidateFileAgainstFile(t *testing.T) {
+	for _, name := range []string{"Simple", "HasRefs", "Circular"} {
+		r, err := vjsonschema.ValidateFileAgainstFile("./schemas/"+name+".json", "./payloads/"+name+"Pass.json")
+		if err != nil {
+			t.Error(err)
+		} else if !r.Valid() {
+			t.Error(name, "expected the passing payload to be valid:", r.Errors())
+		}
+		r, err = vjsonschema.ValidateFileAgainstFile("./schemas/"+name+".json", "./payloads/"+name+"Fail.json")
+		if err != nil {
+			t.Error(err)
+		} else if r.Valid() {
+			t.Error(name, "expected the failing payload to be invalid")
+		}
+	}
+	if _, err := vjsonschema.ValidateFileAgainstFile("./schemas/MissingRefs.json", "./payloads/SimplePass.json"); err == nil {
+		t.Error("expected an error when the schema has missing references")
+	}
+	if _, err := vjsonschema.ValidateFileAgainstFile("./schemas/Simple.json", "./payloads/DoesNotExist.json"); err == nil {
+		t.Error("expected an error when the instance file does not exist")
+	}
+}
+
+func TestGetOriginalSchema(t *testing.T) {
+	factory := vjsonschema.NewBuilder()
+	if err := factory.AddFile("./schemas/Simple.json"); err != nil {
+		t.Fatal(err)
+	}
+	if err := factory.AddSchema("Raw", json.RawMessage(`{"type":"object","definitions":{"Inner":{"type":"string"}}}`)); err != nil {
+		t.Fatal(err)
+	}
+	original, ok := factory.GetOriginalSchema("Simple")
+	if !ok {
+		t.Fatal("expected an original schema for Simple")
+	}
+	if !bytes.Equal(original, readFile("./schemas/Simple.json")) {
+		t.Error("expected the original schema to match the file, found:", string(original))
+	}
+	if bytes.Contains(factory.GetSchemas()["Simple"], []byte(`"definitions"`)) {
+		t.Error("expected GetSchemas to omit definitions")
+	}
+	if original, ok = factory.GetOriginalSchema("Raw"); !ok || !bytes.Contains(original, []byte(`"definitions"`)) {
+		t.Error("expected the original of Raw to include its definitions, found:", string(original))
+	}
+	if _, ok = factory.GetOriginalSchema("Abc"); ok {
+		t.Error("expected no original schema for a definition")
+	}
+	if _, ok = factory.GetOriginalSchema("DoesNotExist"); ok {
+		t.Error("expected no original schema for a schema which does not exist")
+	}
+}
+
+func TestValidateUpdate(t *testing.T) {
+	factory := vjsonschema.NewBuilder()
+	if err := factory.AddFile("./schemas/ReadOnly.json"); err != nil {
+		t.Fatal(err)
+	}
+	v, err := factory.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	existing := []byte(`{"id":1,"name":"a","audit":{"created":"today","note":"x"}}`)
+	cases := []struct {
+		incoming string
+		fields   string
+	}{
+		{`{"id":1,"name":"b","audit":{"created":"today","note":"y"}}`, ""},
+		{`{"id":1,"name":"b"}`, ""},
+		{`{"id":2,"name":"a"}`, "id"},
+		{`{"id":2,"name":"b","audit":{"created":"tomorrow"}}`, "audit.created,id"},
+	}
+	for _, c := range cases {
+		r, err := v.ValidateUpdate("ReadOnly", existing, []byte(c.incoming))
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		fields := make([]string, 0, len(r.Errors()))
+		for _, e := range r.Errors() {
+			if _, ok := e.(*vjsonschema.ReadOnlyError); !ok || e.Type() != "read_only" {
+				t.Error("expected a read only error, found:", e)
+			}
+			fields = append(fields, e.Field())
+		}
+		if strings.Join(fields, ",") != c.fields {
+			t.Errorf("expected errors for fields [%s] in %s, found %v", c.fields, c.incoming, r.Errors())
+		}
+		if r.Valid() != (c.fields == "") {
+			t.Errorf("expected validity of %s to be %v", c.incoming, c.fields == "")
+		}
+	}
+	if r, err := v.ValidateUpdate("ReadOnly", existing, []byte(`{"id":1}`)); err != nil {
+		t.Error(err)
+	} else if r.Valid() {
+		t.Error("expected the incoming instance to also be validated against the schema")
+	}
+}
+
+func TestAddDirLenient(t *testing.T) {
+	factory := vjsonschema.NewBuilder()
+	added, errs := factory.AddDirLenient("./lenient")
+	sort.Strings(added)
+	if strings.Join(added, ",") != "lenient/Good1.json,lenient/Good2.json" {
+		t.Error("expected the good files to be added, found:", added)
+	}
+	if len(errs) != 2 || errs["lenient/Broken.json"] == nil || errs["lenient/Partial.json"] == nil {
+		t.Error("expected errors for the broken and partial files, found:", errs)
+	}
+	if _, ok := factory.GetSchemas()["Fresh"]; ok {
+		t.Error("expected no schemas to be added from the partial file")
+	}
+	v, err := factory.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, err := v.Validate("Good2", []byte(`{"x":"abc"}`)); err != nil {
+		t.Error(err)
+	} else if !r.Valid() {
+		t.Error("expected the instance to be valid:", r.Errors())
+	}
+
+	if err = vjsonschema.NewBuilder().AddDir("./lenient"); err == nil {
+		t.Error("expected AddDir to fail on the broken file")
+	}
+}
+
+func TestValidateParam(t *testing.T) {
+	factory := vjsonschema.NewBuilder()
+	if err := factory.AddSchema("Ids", []byte(`{"type":"array","items":{"type":"integer","maximum":10}}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := factory.AddSchema("Color", []byte(`{
+		"type": "object",
+		"required": ["R"],
+		"additionalProperties": false,
+		"properties": {
+			"R": {"type": "integer"},
+			"G": {"type": "integer"},
+			"Name": {"type": "string"}
+		}
+	}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := factory.AddSchema("Flag", []byte(`{"type":"boolean"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := factory.AddSchema("Tags", []byte(`{"type":"array","items":{"type":"string"},"maxItems":2}`)); err != nil {
+		t.Fatal(err)
+	}
+	v, err := factory.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		schema  string
+		raw     string
+		style   string
+		explode bool
+		valid   bool
+	}{
+		{"Ids", "id=3&id=4&id=5", "form", true, true},
+		{"Ids", "id=3&id=40", "form", true, false},
+		{"Ids", "id=3&id=x", "form", true, false},
+		{"Ids", "3,4,5", "form", false, true},
+		{"Ids", "3%2C4", "form", false, false},
+		{"Ids", "3,40", "form", false, false},
+		{"Tags", "a%2Cb,c", "form", false, true},
+		{"Tags", "a%7Cb|c", "pipeDelimited", false, true},
+		{"Ids", "3%204", "spaceDelimited", false, true},
+		{"Ids", "3|4", "pipeDelimited", false, true},
+		{"Color", "color[R]=100&color[G]=200", "deepObject", true, true},
+		{"Color", "color[R]=100&color[Name]=dark%20red", "deepObject", true, true},
+		{"Color", "color[G]=200", "deepObject", true, false},
+		{"Color", "color[R]=1&color[B]=2", "deepObject", true, false},
+		{"Color", "R=100&G=200", "form", true, true},
+		{"Color", "R,100,G,200", "form", false, true},
+		{"Flag", "true", "form", false, true},
+		{"Flag", "flag=false", "form", true, true},
+		{"Flag", "yes", "form", false, false},
+	}
+	for _, c := range cases {
+		r, err := v.ValidateParam(c.schema, c.raw, c.style, c.explode)
+		if err != nil {
+			t.Error(c.raw, err)
+		} else if r.Valid() != c.valid {
+			t.Errorf("expected validity of %s (%s, explode=%v) against %s to be %v: %v", c.raw, c.style, c.explode, c.schema, c.valid, r.Errors())
+		}
+	}
+	if _, err = v.ValidateParam("Ids", "1,2", "matrix", false); err == nil {
+		t.Error("expected an error for an unsupported style")
+	}
+	if _, err = v.ValidateParam("Color", "a[R]=1&b[G]=2", "deepObject", true); err == nil {
+		t.Error("expected an error for deepObject pairs of multiple parameters")
+	}
+	if _, err = v.ValidateParam("Ids", "a=1&b=2", "form", true); err == nil {
+		t.Error("expected an error for exploded array pairs of multiple parameters")
+	}
+	if _, err = v.ValidateParam("Ids", "1,2", "deepObject", true); err == nil {
+		t.Error("expected an error for deepObject with an array")
+	}
+	if _, err = v.ValidateParam("Color", "R,100,G", "form", false); err == nil {
+		t.Error("expected an error for an object with a key missing its value")
+	}
+}
@@ -1,18 +1,114 @@
 package vjsonschema
 
 import (
+	"encoding/json"
 	"fmt"
+	"github.com/pkg/errors"
 	"regexp"
+	"strings"
 )
 
 var (
-	refRegex = regexp.MustCompile(`"\$ref"\s*:\s*"{([^"]*?)}"`)
+	refRegex       = regexp.MustCompile(`"\$ref"\s*:\s*"{([^"]*?)}(#/[^"]*)?"`)
+	anchorRefRegex = regexp.MustCompile(`"\$ref"\s*:\s*"#([A-Za-z_][-A-Za-z0-9._]*)"`)
+	varRegex       = regexp.MustCompile(`^\$\{([A-Za-z_][A-Za-z0-9_]*)\}$`)
 )
 
+// Keywords whose values are annotations or instance data rather than constraints.
+// Strings of the form "${varName}" within these are literal values, not variable placeholders.
+var literalKeywords = map[string]bool{
+	"$comment":    true,
+	"const":       true,
+	"default":     true,
+	"description": true,
+	"enum":        true,
+	"example":     true,
+	"examples":    true,
+	"title":       true,
+}
+
+// Keywords whose values are objects mapping names to schemas.
+// The names within these are never treated as keywords.
+var schemaMapKeywords = map[string]bool{
+	"definitions":       true,
+	"dependencies":      true,
+	"patternProperties": true,
+	"properties":        true,
+}
+
 // Replaces all $ref values that are surrounded by { and } using the provided replacement function.
+// Any json pointer following the reference, such as in "{Name}#/properties/x", is kept after the replacement.
 func SchemaRefReplace(schema []byte, replaceFunc func(ref string) string) []byte {
 	return refRegex.ReplaceAllFunc(schema, func(match []byte) []byte {
-		ref := refRegex.FindSubmatch(match)[1]
-		return []byte(fmt.Sprintf(`"$ref":"%s"`, replaceFunc(string(ref))))
+		m := refRegex.FindSubmatch(match)
+		return []byte(fmt.Sprintf(`"$ref":"%s%s"`, replaceFunc(string(m[1])), m[2]))
+	})
+}
+
+// Replaces all variable placeholders in the constraints of the schema with the json encoding of the variable.
+func substituteVars(schema []byte, vars map[string]interface{}) ([]byte, error) {
+	var m interface{}
+	if err := json.Unmarshal(schema, &m); err != nil {
+		return nil, errors.WithMessage(err, "failed to parse schema as json")
+	}
+	missing := make([]string, 0)
+	m = replaceVars(m, func(name string) interface{} {
+		value, ok := vars[name]
+		if !ok {
+			missing = append(missing, name)
+		}
+		return value
+	})
+	if len(missing) > 0 {
+		return nil, errors.New("missing vars: " + strings.Join(missing, ", "))
+	}
+	out, err := json.Marshal(m)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to marshal schema with vars as json")
+	}
+	return out, nil
+}
+
+// Returns true if the constraints of the schema contain any variable placeholders.
+func hasVars(schema interface{}) bool {
+	found := false
+	replaceVars(schema, func(name string) interface{} {
+		found = true
+		return nil
 	})
+	return found
+}
+
+// Returns a copy of the schema with every string of the form "${varName}" replaced by the result of 'replace'.
+// Strings within literalKeywords, such as 'default' or 'enum', are left as they are.
+func replaceVars(schema interface{}, replace func(name string) interface{}) interface{} {
+	switch s := schema.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(s))
+		for k, x := range s {
+			if literalKeywords[k] {
+				out[k] = x
+			} else if m, ok := x.(map[string]interface{}); ok && schemaMapKeywords[k] {
+				schemas := make(map[string]interface{}, len(m))
+				for name, sub := range m {
+					schemas[name] = replaceVars(sub, replace)
+				}
+				out[k] = schemas
+			} else {
+				out[k] = replaceVars(x, replace)
+			}
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(s))
+		for i, x := range s {
+			out[i] = replaceVars(x, replace)
+		}
+		return out
+	case string:
+		if match := varRegex.FindStringSubmatch(s); match != nil {
+			return replace(match[1])
+		}
+	}
+	return schema
 }
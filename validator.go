@@ -6,28 +6,320 @@
 package vjsonschema
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"github.com/pkg/errors"
 	"github.com/xeipuuv/gojsonschema"
+	"reflect"
+	"sort"
+	"sync"
 )
 
 // An object that is capable of validating json against schemas.
 type Validator interface {
 	// Validate that a particular json blob conforms to the given schema.
 	Validate(schemaName string, instance []byte) (*gojsonschema.Result, error)
+
+	// Validate that a particular json blob conforms to the given schema,
+	// additionally collecting warnings which do not affect the validity of the instance.
+	// A warning is produced for every field present in the instance whose schema is marked with 'deprecated: true'.
+	ValidateWithWarnings(schemaName string, instance []byte) (errs []ValidationError, warnings []ValidationError, valid bool, err error)
+
+	// Returns true if the schema is marked with 'deprecated: true'.
+	IsDeprecated(schemaName string) bool
+
+	// Returns the schema with every compliant reference recursively replaced by the schema that it refers to.
+	// Circular references are replaced by a json pointer reference to where the schema was first inlined.
+	EffectiveSchema(schemaName string) ([]byte, error)
+
+	// Return the sorted names of all schemas which may be validated against.
+	SchemaNames() []string
+
+	// Validate that a particular json blob conforms to the given schema,
+	// after substituting variables into the schema and any schemas that it references.
+	// Any string value of a constraint of the form "${varName}" is replaced with the json encoding of vars["varName"],
+	// so that {"maxLength": "${limit}"} with a limit of 10 becomes {"maxLength": 10}.
+	// Annotations and instance data, such as 'default', 'const', 'enum' and 'examples', never contain placeholders.
+	// Compiled schemas are cached for each distinct set of vars for the lifetime of the validator.
+	// The cache is never evicted, so its size grows with every distinct set of vars that is used.
+	ValidateWithVars(schemaName string, instance []byte, vars map[string]interface{}) (*gojsonschema.Result, error)
+
+	// Report only the fields of the instance which are explicitly null where their schema does not allow null.
+	// These are reported with the type "null_not_allowed", distinguishing them from the "required" errors
+	// which Validate reports for fields that are absent.
+	ValidateNullability(schemaName string, instance []byte) ([]ValidationError, error)
+
+	// Validate that 'incoming' conforms to the given schema, and that it does not change
+	// the value of any field marked with 'readOnly: true' from its value in 'existing'.
+	// Changed readOnly fields are added to the result as a ReadOnlyError.
+	// ReadOnly fields which are omitted from 'incoming' are not considered to be changed.
+	ValidateUpdate(schemaName string, existing, incoming []byte) (*gojsonschema.Result, error)
+
+	// Validate a raw OpenAPI parameter by first parsing it into json according to its 'style' and 'explode'.
+	// Supported styles are form, spaceDelimited, pipeDelimited and deepObject.
+	// Exploded and deepObject parameters are given as the pairs of a query string, such as "id=1&id=2" or "color[R]=100&color[G]=200",
+	// while other parameters are given as their value alone, such as "1,2".
+	// The pairs must belong to this parameter alone, as the properties of exploded form objects are not prefixed with its name.
+	// Percent-encoded delimiters, such as %2C for form, are part of a value rather than a delimiter,
+	// except for spaceDelimited, where the space must always be encoded as %20.
+	// Values are converted to the types given by the schema, where possible.
+	ValidateParam(schemaName string, rawValue string, style string, explode bool) (*gojsonschema.Result, error)
+}
+
+// The error added to a result when an update changes the value of a readOnly field.
+type ReadOnlyError struct {
+	gojsonschema.ResultErrorFields
+}
+
+// A single problem found with an instance while validating it against a schema.
+type ValidationError struct {
+	// The field which the error applies to, or "(root)" for the instance itself.
+	Field string
+	// The type of the error, such as "required" or "deprecated".
+	Type string
+	// A human readable description of the error.
+	Description string
+	// The value from the instance which caused the error.
+	Value interface{}
+}
+
+func (e ValidationError) String() string {
+	return e.Field + ": " + e.Description
 }
 
 type validator struct {
 	schemas map[string]*gojsonschema.Schema
+	sources map[string]registeredSchema
+
+	varsMu    sync.Mutex
+	varsCache map[string]*gojsonschema.Schema
 }
 
 func (v *validator) Validate(schemaName string, instance []byte) (*gojsonschema.Result, error) {
 	if schema, ok := v.schemas[schemaName]; !ok {
+		if _, ok := v.sources[schemaName]; ok {
+			return nil, errors.New("schema requires variables, use ValidateWithVars for schema with name: " + schemaName)
+		}
 		return nil, errors.New("schema does not exist with name: " + schemaName)
 	} else {
 		return schema.Validate(gojsonschema.NewBytesLoader(instance))
 	}
 }
 
+func (v *validator) SchemaNames() []string {
+	out := make([]string, 0, len(v.sources))
+	for name := range v.sources {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func (v *validator) ValidateWithVars(schemaName string, instance []byte, vars map[string]interface{}) (*gojsonschema.Result, error) {
+	if schema, ok := v.schemas[schemaName]; ok {
+		return schema.Validate(gojsonschema.NewBytesLoader(instance))
+	}
+	if _, ok := v.sources[schemaName]; !ok {
+		return nil, errors.New("schema does not exist with name: " + schemaName)
+	}
+	b, err := json.Marshal(vars)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to marshal vars as json")
+	}
+	hash := sha256.Sum256(b)
+	key := schemaName + ":" + hex.EncodeToString(hash[:])
+
+	v.varsMu.Lock()
+	schema, ok := v.varsCache[key]
+	v.varsMu.Unlock()
+	if !ok {
+		sources := make(map[string]registeredSchema)
+		if err = substituteReferencedVars(v.sources, schemaName, vars, sources); err != nil {
+			return nil, err
+		}
+		if schema, err = compileSchema(sources, schemaName); err != nil {
+			return nil, err
+		}
+		v.varsMu.Lock()
+		if cached, ok := v.varsCache[key]; ok {
+			schema = cached
+		} else {
+			if v.varsCache == nil {
+				v.varsCache = make(map[string]*gojsonschema.Schema)
+			}
+			v.varsCache[key] = schema
+		}
+		v.varsMu.Unlock()
+	}
+	return schema.Validate(gojsonschema.NewBytesLoader(instance))
+}
+
+func (v *validator) ValidateNullability(schemaName string, instance []byte) ([]ValidationError, error) {
+	schema, err := parseSchema(v.sources, schemaName)
+	if err != nil {
+		return nil, err
+	}
+	var inst interface{}
+	if err = json.Unmarshal(instance, &inst); err != nil {
+		return nil, errors.WithMessage(err, "failed to parse instance as json")
+	}
+	errs := make([]ValidationError, 0)
+	reported := make(map[string]struct{})
+	walkSchema(v.sources, schema, inst, nil, func(s map[string]interface{}, value interface{}, path []string) bool {
+		if value != nil || len(path) == 0 {
+			return true
+		}
+		field := fieldName(path)
+		if _, ok := reported[field]; !ok && !allowsNull(v.sources, s) {
+			reported[field] = struct{}{}
+			errs = append(errs, ValidationError{
+				Field:       field,
+				Type:        "null_not_allowed",
+				Description: "Null is not allowed",
+			})
+		}
+		return false
+	})
+	return errs, nil
+}
+
+func (v *validator) ValidateUpdate(schemaName string, existing, incoming []byte) (*gojsonschema.Result, error) {
+	result, err := v.Validate(schemaName, incoming)
+	if err != nil {
+		return nil, err
+	}
+	schema, err := parseSchema(v.sources, schemaName)
+	if err != nil {
+		return nil, err
+	}
+	var oldInst, newInst interface{}
+	if err = json.Unmarshal(existing, &oldInst); err != nil {
+		return nil, errors.WithMessage(err, "failed to parse existing instance as json")
+	}
+	if err = json.Unmarshal(incoming, &newInst); err != nil {
+		return nil, errors.WithMessage(err, "failed to parse incoming instance as json")
+	}
+	reported := make(map[string]struct{})
+	walkSchema(v.sources, schema, newInst, nil, func(s map[string]interface{}, value interface{}, path []string) bool {
+		if s["readOnly"] != true || len(path) == 0 {
+			return true
+		}
+		field := fieldName(path)
+		if _, ok := reported[field]; ok {
+			return false
+		}
+		if old, ok := lookupPath(oldInst, path); !ok || !reflect.DeepEqual(old, value) {
+			reported[field] = struct{}{}
+			context := gojsonschema.NewJsonContext("(root)", nil)
+			for _, p := range path {
+				context = gojsonschema.NewJsonContext(p, context)
+			}
+			e := &ReadOnlyError{}
+			e.SetType("read_only")
+			e.SetContext(context)
+			e.SetValue(value)
+			e.SetDescriptionFormat("Field is readOnly and may not be changed")
+			result.AddError(e, gojsonschema.ErrorDetails{"field": field})
+		}
+		return false
+	})
+	return result, nil
+}
+
+func (v *validator) IsDeprecated(schemaName string) bool {
+	return v.sources[schemaName].deprecated
+}
+
+func (v *validator) EffectiveSchema(schemaName string) ([]byte, error) {
+	if _, ok := v.sources[schemaName]; !ok {
+		return nil, errors.New("schema does not exist with name: " + schemaName)
+	}
+	schema, err := inlineRefs(v.sources, map[string]interface{}{"$ref": "{" + schemaName + "}"}, "#", make(map[string]string))
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to inline references of schema with name: "+schemaName)
+	}
+	return json.Marshal(schema)
+}
+
+func (v *validator) ValidateWithWarnings(schemaName string, instance []byte) (errs []ValidationError, warnings []ValidationError, valid bool, err error) {
+	result, err := v.Validate(schemaName, instance)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	for _, e := range result.Errors() {
+		errs = append(errs, ValidationError{
+			Field:       e.Field(),
+			Type:        e.Type(),
+			Description: e.Description(),
+			Value:       e.Value(),
+		})
+	}
+	schema, err := parseSchema(v.sources, schemaName)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	var inst interface{}
+	if err = json.Unmarshal(instance, &inst); err != nil {
+		return nil, nil, false, errors.WithMessage(err, "failed to parse instance as json")
+	}
+	warned := make(map[string]struct{})
+	walkSchema(v.sources, schema, inst, nil, func(s map[string]interface{}, value interface{}, path []string) bool {
+		field := fieldName(path)
+		if _, ok := warned[field]; !ok && len(path) > 0 && s["deprecated"] == true {
+			warned[field] = struct{}{}
+			warnings = append(warnings, ValidationError{
+				Field:       field,
+				Type:        "deprecated",
+				Description: "Field is deprecated",
+				Value:       value,
+			})
+		}
+		return true
+	})
+	return errs, warnings, result.Valid(), nil
+}
+
+// Adds the schema and every schema that it transitively references to 'out',
+// substituting vars into those which contain variable placeholders.
+func substituteReferencedVars(schemas map[string]registeredSchema, name string, vars map[string]interface{}, out map[string]registeredSchema) error {
+	if _, ok := out[name]; ok {
+		return nil
+	}
+	s := schemas[name]
+	out[name] = s
+	if s.parametric {
+		var err error
+		if s.source, err = substituteVars(s.source, vars); err != nil {
+			return errors.WithMessage(err, "failed to substitute vars into schema with name: "+name)
+		}
+		out[name] = s
+	}
+	for n := range s.requiredReferences {
+		if err := substituteReferencedVars(schemas, n, vars, out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func compileSchema(schemas map[string]registeredSchema, name string) (*gojsonschema.Schema, error) {
+	s := schemas[name]
+	loader := gojsonschema.NewSchemaLoader()
+	schemasAdded := make(map[string]bool, 7)
+	schemasAdded[name] = false
+	for n := range s.requiredReferences {
+		if err := addSchemasCompile(schemas, &schemasAdded, loader, n); err != nil {
+			return nil, err
+		}
+	}
+	schema, err := loader.Compile(gojsonschema.NewBytesLoader(s.compiledSource()))
+	if err != nil {
+		return nil, errors.WithMessage(err, "gojsonschema: failed to compile schema with name: "+name)
+	}
+	return schema, nil
+}
+
 func addSchemasCompile(schemas map[string]registeredSchema, schemasAdded *map[string]bool, loader *gojsonschema.SchemaLoader, name string) error {
 	s := schemas[name]
 	for reqRef := range s.requiredReferences {
@@ -40,7 +332,7 @@ func addSchemasCompile(schemas map[string]registeredSchema, schemasAdded *map[st
 	}
 	if !(*schemasAdded)[name] {
 		(*schemasAdded)[name] = true
-		if err := loader.AddSchema(refNameConvert(name), gojsonschema.NewBytesLoader(SchemaRefReplace(s.source, refNameConvert))); err != nil {
+		if err := loader.AddSchema(refNameConvert(name), gojsonschema.NewBytesLoader(s.compiledSource())); err != nil {
 			return errors.WithMessage(err, "gojsonschema: failed to load schema with name: "+name)
 		}
 	}
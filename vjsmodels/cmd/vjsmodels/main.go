@@ -6,13 +6,21 @@ import (
 	"github.com/tjbrockmeyer/vjsonschema/vjsmodels"
 	"gopkg.in/alecthomas/kingpin.v2"
 	"io/ioutil"
+	"path/filepath"
 )
 
 var (
-	outfile = kingpin.Arg("output", "name of the file to write models to").Required().String()
-	pkg     = kingpin.Arg("package", "name of the package to use for the generated models").Required().String()
-	dirs    = kingpin.Flag("dir", "directory to gather schemas from (must be .json files)").ExistingDirs()
-	files   = kingpin.Flag("file", "file to gather schemas from (must be a .json file)").ExistingFiles()
+	outfile  = kingpin.Arg("output", "name of the file (or directory with --split) to write models to").Required().String()
+	pkg      = kingpin.Arg("package", "name of the package to use for the generated models").Required().String()
+	dirs     = kingpin.Flag("dir", "directory to gather schemas from (must be .json files)").ExistingDirs()
+	files    = kingpin.Flag("file", "file to gather schemas from (must be a .json file)").ExistingFiles()
+	enums    = kingpin.Flag("enums", "generate named types with constants for string enums").Bool()
+	redact   = kingpin.Flag("redact-write-only", "generate String methods which redact writeOnly properties").Bool()
+	reqFirst = kingpin.Flag("required-first", "order struct fields with required properties first").Bool()
+	patterns = kingpin.Flag("newtype-patterns", "generate named string types which enforce the 'pattern' of strings").Bool()
+	registry = kingpin.Flag("registry", "generate a RegisterSchemas function which adds the schemas to a vjsonschema.Builder").Bool()
+	doc      = kingpin.Flag("package-doc", "comment to write before the package clause").String()
+	split    = kingpin.Flag("split", "write each model to its own zz_generated. file in the output directory").Bool()
 )
 
 func main() {
@@ -29,7 +37,33 @@ func main() {
 			panic(errors.WithMessage(err, "failed to add file"))
 		}
 	}
-	b, err := vjsmodels.Generate(*pkg, builder.GetSchemas())
+	opts := vjsmodels.GenerateOptions{
+		Enums:           *enums,
+		RedactWriteOnly: *redact,
+		RequiredFirst:   *reqFirst,
+		NewtypePatterns: *patterns,
+		PackageDoc:      *doc,
+	}
+	if *split {
+		if *registry {
+			panic(errors.New("--registry cannot be used with --split"))
+		}
+		out, err := vjsmodels.GenerateFiles(*pkg, builder.GetSchemas(), opts)
+		if err != nil {
+			panic(errors.WithMessage(err, "failed to generate models"))
+		}
+		for name, b := range out {
+			if err = ioutil.WriteFile(filepath.Join(*outfile, name), b, 0744); err != nil {
+				panic(errors.WithMessage(err, "failed to write models to file"))
+			}
+		}
+		return
+	}
+	generate := vjsmodels.GenerateWithOptions
+	if *registry {
+		generate = vjsmodels.GenerateWithRegistry
+	}
+	b, err := generate(*pkg, builder.GetSchemas(), opts)
 	if err != nil {
 		panic(errors.WithMessage(err, "failed to generate models"))
 	}
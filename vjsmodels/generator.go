@@ -8,6 +8,7 @@ import (
 	"go/format"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -17,12 +18,59 @@ const (
 	isAcceptNone
 	isArray
 	isObject
+	isEnum
+	isPattern
 )
 
 var (
-	azRegex = regexp.MustCompile(`^[A-Za-z]`)
+	azRegex      = regexp.MustCompile(`^[A-Za-z]`)
+	nonWordRegex = regexp.MustCompile(`[^A-Za-z0-9]+`)
 )
 
+// Options which enable additional features of the generated models.
+type GenerateOptions struct {
+	// Generate a named string type with constants for every top-level schema which is a string 'enum'.
+	// The types implement encoding.TextMarshaler and encoding.TextUnmarshaler, which allows them to be used as json map keys.
+	// Unmarshalling rejects values outside of the enum, while marshalling writes any value as it is,
+	// so that the zero value of a struct containing an enum can still be serialized.
+	Enums bool
+
+	// Generate a String method for every top-level object with properties marked 'writeOnly: true',
+	// including those of nested objects, which formats the object with the values of those properties replaced by ***.
+	// Arrays and maps whose items have writeOnly properties are replaced by *** entirely.
+	// Objects combining other schemas with allOf, anyOf or oneOf are also given a String method,
+	// so that the String method of an embedded type is not promoted in place of formatting every field.
+	RedactWriteOnly bool
+
+	// Order the fields of generated structs with required properties first, followed by optional properties.
+	// Both groups remain in alphabetical order. This does not affect the order of properties in serialized json.
+	RequiredFirst bool
+
+	// Generate a named string type for every string with a 'pattern', with an UnmarshalJSON method
+	// which rejects values that do not match the pattern. Top-level schemas use their own name,
+	// while properties are named by their object followed by the property name.
+	// Patterns which are not valid go regular expressions remain plain strings.
+	NewtypePatterns bool
+
+	// A comment which is written before the package clause of the generated source.
+	// When generating multiple files, it is written to its own file, zz_generated.doc.go.
+	PackageDoc string
+}
+
+type generator struct {
+	opts    GenerateOptions
+	schemas map[string]*jsonSchema
+	decls   map[string]map[string]decl
+	// Identifiers declared in addition to the types of the schemas, mapped to a description of what declared them.
+	names map[string]string
+}
+
+// Source code which is generated in addition to a type, such as its methods.
+type decl struct {
+	src     string
+	imports []string
+}
+
 type field struct {
 	name     string
 	required bool
@@ -33,9 +81,13 @@ type jsonSchema struct {
 	jsonSchemaBase
 
 	nullable    bool
+	typeName    string
+	fieldName   string
 	goType      string
 	specialType int
 	fields      []field
+	// The schemas which were combined into a struct by allOf, anyOf or oneOf.
+	ofSchemas []*jsonSchema
 }
 
 type jsonSchemaBase struct {
@@ -51,6 +103,15 @@ type jsonSchemaBase struct {
 	OneOf                []*jsonSchema          `json:"oneOf"`
 	AllOf                []*jsonSchema          `json:"allOf"`
 	AnyOf                []*jsonSchema          `json:"anyOf"`
+	Enum                 []interface{}          `json:"enum"`
+	Discriminator        *discriminator         `json:"discriminator"`
+	WriteOnly            bool                   `json:"writeOnly"`
+	Pattern              string                 `json:"pattern"`
+}
+
+type discriminator struct {
+	PropertyName string            `json:"propertyName"`
+	Mapping      map[string]string `json:"mapping"`
 }
 
 func (s *jsonSchema) UnmarshalJSON(b []byte) error {
@@ -73,9 +134,9 @@ func (s *jsonSchema) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
-func (s *jsonSchema) handleOneAnyAllOf(ofSchemas []*jsonSchema, schemas map[string]*jsonSchema, required bool, keyName string) error {
+func (s *jsonSchema) handleOneAnyAllOf(ofSchemas []*jsonSchema, g *generator, required bool, keyName string) error {
 	for i, s2 := range ofSchemas {
-		if err := s2.getGoType(schemas, true); err != nil {
+		if err := s2.getGoType(g, true); err != nil {
 			return errors.WithMessagef(err, "schema '%v'", i)
 		}
 		if s2.specialType != isObject {
@@ -84,6 +145,7 @@ func (s *jsonSchema) handleOneAnyAllOf(ofSchemas []*jsonSchema, schemas map[stri
 		}
 	}
 	s.specialType = isObject
+	s.ofSchemas = ofSchemas
 	var builder strings.Builder
 	builder.WriteString("struct{\n")
 	for i, s2 := range ofSchemas {
@@ -92,7 +154,7 @@ func (s *jsonSchema) handleOneAnyAllOf(ofSchemas []*jsonSchema, schemas map[stri
 			ref, _ = isCompliantRef(s2.Ref)
 		}
 		builder.WriteString(fmt.Sprintf("// %s: schema #%v\n", keyName, i))
-		if _, ok := schemas[ref]; ok {
+		if _, ok := g.schemas[ref]; ok {
 			builder.WriteString("*" + toIdentifier(ref) + "\n")
 		} else {
 			t := strings.Split(s2.goType, "\n")
@@ -104,7 +166,7 @@ func (s *jsonSchema) handleOneAnyAllOf(ofSchemas []*jsonSchema, schemas map[stri
 	return nil
 }
 
-func (s *jsonSchema) handleArray(schemas map[string]*jsonSchema, required bool) error {
+func (s *jsonSchema) handleArray(g *generator, required bool) error {
 	s.specialType = isArray
 	if _, ok := s.Items.([]interface{}); ok {
 		b, _ := json.Marshal(s.Items)
@@ -112,7 +174,7 @@ func (s *jsonSchema) handleArray(schemas map[string]*jsonSchema, required bool)
 		if err := json.Unmarshal(b, &oneOf); err != nil {
 			return errors.New("keyword 'items' should be one of {schema, []schema}")
 		}
-		if err := s.handleOneAnyAllOf(oneOf, schemas, required, "oneOf"); err != nil {
+		if err := s.handleOneAnyAllOf(oneOf, g, required, "oneOf"); err != nil {
 			return errors.WithMessage(err, "keyword 'items'")
 		}
 		s.goType = "[]" + s.goType
@@ -123,19 +185,126 @@ func (s *jsonSchema) handleArray(schemas map[string]*jsonSchema, required bool)
 		if err := json.Unmarshal(b, s2); err != nil {
 			return errors.New("keyword 'items' should be one of {schema, []schema}")
 		}
-		err := s2.getGoType(schemas, true)
+		if s.typeName != "" && s2.Discriminator != nil && s2.OneOf != nil {
+			if ok, err := s.handleDiscriminatedArray(s2, g); err != nil {
+				return errors.WithMessage(err, "keyword 'items'")
+			} else if ok {
+				return nil
+			}
+		}
+		err := s2.getGoType(g, true)
 		s.goType = "[]" + s2.goType
 		return errors.WithMessage(err, "keyword 'items'")
 	}
 }
 
-func (s *jsonSchema) handleObject(schemas map[string]*jsonSchema, required bool) error {
+// Handles an array whose items are a 'oneOf' with a 'discriminator', where every variant is a reference to an object.
+// The array is given an UnmarshalJSON method which decodes each item into the variant chosen by the discriminator.
+// Only top-level arrays are handled, as the method requires a named type; arrays within properties are left as they were.
+// Returns false if the items do not meet the requirements.
+func (s *jsonSchema) handleDiscriminatedArray(items *jsonSchema, g *generator) (bool, error) {
+	prop := items.Discriminator.PropertyName
+	if prop == "" {
+		return false, errors.New("keyword 'discriminator' requires 'propertyName'")
+	}
+	values := make(map[string]string, len(items.OneOf))
+	for _, variant := range items.OneOf {
+		ref, ok := isCompliantRef(variant.Ref)
+		if !ok {
+			return false, nil
+		}
+		if err := variant.getGoType(g, true); err != nil {
+			return false, errors.WithMessage(err, "keyword 'oneOf'")
+		}
+		if variant.specialType != isObject {
+			return false, nil
+		}
+		values[ref] = ref
+	}
+	if items.Discriminator.Mapping != nil {
+		mapped := make(map[string]string, len(items.Discriminator.Mapping))
+		for value, r := range items.Discriminator.Mapping {
+			ref, ok := isCompliantRef(r)
+			if _, isVariant := values[ref]; !ok || !isVariant {
+				return false, fmt.Errorf("keyword 'discriminator': mapping for '%s' must refer to one of the 'oneOf' schemas", value)
+			}
+			mapped[value] = ref
+		}
+		values = mapped
+	}
+	discValues := make([]string, 0, len(values))
+	variants := make([]string, 0, len(items.OneOf))
+	for value := range values {
+		discValues = append(discValues, value)
+	}
+	sort.Strings(discValues)
+	for _, variant := range items.OneOf {
+		ref, _ := isCompliantRef(variant.Ref)
+		variants = append(variants, "*"+toIdentifier(ref))
+	}
+
+	wrapper := s.typeName + "Item"
+	if err := g.reserve(wrapper, "the items of "+s.typeName); err != nil {
+		return false, err
+	}
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf(`// %[1]s holds a single item of %[2]s, whose variant is chosen by the %[3]q property.
+// Value is one of: %[4]s
+type %[1]s struct {
+	Value interface{}
+}
+
+func (w %[1]s) MarshalJSON() ([]byte, error) {
+	return json.Marshal(w.Value)
+}
+
+func (s *%[2]s) UnmarshalJSON(b []byte) error {
+	var items []json.RawMessage
+	if err := json.Unmarshal(b, &items); err != nil {
+		return err
+	}
+	if items == nil {
+		*s = nil
+		return nil
+	}
+	out := make(%[2]s, 0, len(items))
+	for i, item := range items {
+		var d struct {
+			%[5]s string `+"`json:\"%[3]s\"`"+`
+		}
+		if err := json.Unmarshal(item, &d); err != nil {
+			return fmt.Errorf("item %%d of %[2]s: %%v", i, err)
+		}
+		var v interface{}
+		switch d.%[5]s {
+`, wrapper, s.typeName, prop, strings.Join(variants, ", "), toIdentifier(prop)))
+	for _, value := range discValues {
+		b.WriteString(fmt.Sprintf("case %q:\nv = new(%s)\n", value, toIdentifier(values[value])))
+	}
+	b.WriteString(fmt.Sprintf(`		default:
+			return fmt.Errorf("item %%d of %[1]s: unknown value for property %[2]s: %%q", i, d.%[3]s)
+		}
+		if err := json.Unmarshal(item, v); err != nil {
+			return fmt.Errorf("item %%d of %[1]s: %%v", i, err)
+		}
+		out = append(out, %[4]s{Value: v})
+	}
+	*s = out
+	return nil
+}`, s.typeName, prop, toIdentifier(prop), wrapper))
+	g.addDecl(s.typeName, "discriminator", b.String(), "encoding/json", "fmt")
+	s.specialType = isArray
+	s.goType = "[]" + wrapper
+	return true, nil
+}
+
+func (s *jsonSchema) handleObject(g *generator, required bool) error {
 	if s.AdditionalProperties != nil {
 		if s.AdditionalProperties.specialType == isAcceptAll {
 			s.goType = "map[string]interface{}"
 			return nil
 		} else if s.AdditionalProperties.specialType != isAcceptNone {
-			if err := s.AdditionalProperties.getGoType(schemas, true); err != nil {
+			if err := s.AdditionalProperties.getGoType(g, true); err != nil {
 				return errors.WithMessage(err, "keyword 'additionalProperties'")
 			}
 			s.goType = "map[string]" + s.AdditionalProperties.goType
@@ -146,7 +315,7 @@ func (s *jsonSchema) handleObject(schemas map[string]*jsonSchema, required bool)
 
 	if s.PatternProperties != nil {
 		if len(s.PatternProperties) == 1 {
-			if err := s.getGoType(schemas, true); err != nil {
+			if err := s.getGoType(g, true); err != nil {
 				return errors.WithMessage(err, "keyword 'patternProperties'")
 			}
 			s.goType = "map[string]" + s.goType
@@ -174,12 +343,24 @@ func (s *jsonSchema) handleObject(schemas map[string]*jsonSchema, required bool)
 			props = append(props, name)
 		}
 		sort.Strings(props)
+		if g.opts.RequiredFirst {
+			sort.SliceStable(props, func(i, j int) bool {
+				_, iRequired := reqList[props[i]]
+				_, jRequired := reqList[props[j]]
+				return iRequired && !jRequired
+			})
+		}
+		s.fields = make([]field, 0, len(props))
 		for _, name := range props {
 			schema := s.Properties[name]
 			_, isRequired := reqList[name]
-			if err := schema.getGoType(schemas, isRequired); err != nil {
+			if s.typeName != "" {
+				schema.fieldName = s.typeName + toIdentifier(name)
+			}
+			if err := schema.getGoType(g, isRequired); err != nil {
 				return errors.WithMessage(err, "keyword 'properties."+name+"'")
 			}
+			s.fields = append(s.fields, field{name: name, required: isRequired, schema: schema})
 			var omitEmpty string
 			if !isRequired {
 				omitEmpty = ",omitempty"
@@ -194,7 +375,7 @@ func (s *jsonSchema) handleObject(schemas map[string]*jsonSchema, required bool)
 	return nil
 }
 
-func (s *jsonSchema) handleType(kwType interface{}, schemas map[string]*jsonSchema, required bool) error {
+func (s *jsonSchema) handleType(kwType interface{}, g *generator, required bool) error {
 	if t, ok := kwType.(string); ok {
 		switch t {
 		case "null":
@@ -208,16 +389,16 @@ func (s *jsonSchema) handleType(kwType interface{}, schemas map[string]*jsonSche
 		case "string":
 			s.goType = "string"
 		case "array":
-			return s.handleArray(schemas, required)
+			return s.handleArray(g, required)
 		case "object":
-			return s.handleObject(schemas, required)
+			return s.handleObject(g, required)
 		default:
 			return errors.New("valid values for keyword 'type' are {null, boolean, integer, number, string, array, object}")
 		}
 		return nil
 	} else if l, ok := kwType.([]interface{}); ok {
 		if len(l) == 1 {
-			return s.handleType(l[0], schemas, required)
+			return s.handleType(l[0], g, required)
 		}
 		var types = make(map[string]struct{})
 		for _, t := range l {
@@ -236,7 +417,7 @@ func (s *jsonSchema) handleType(kwType interface{}, schemas map[string]*jsonSche
 			}
 		}
 		for t := range types {
-			return s.handleType(t, schemas, required)
+			return s.handleType(t, g, required)
 		}
 		s.goType = "struct{}"
 		return nil
@@ -245,14 +426,14 @@ func (s *jsonSchema) handleType(kwType interface{}, schemas map[string]*jsonSche
 	}
 }
 
-func (s *jsonSchema) getGoType(schemas map[string]*jsonSchema, required bool) error {
+func (s *jsonSchema) getGoType(g *generator, required bool) error {
 	if s.Ref != "" {
 		if s2Name, ok := isCompliantRef(s.Ref); ok {
-			s2, ok := schemas[s2Name]
+			s2, ok := g.schemas[s2Name]
 			if !ok {
 				return fmt.Errorf("schema \"%s\" is not defined", s2Name)
 			}
-			if err := s2.getGoType(schemas, required); err != nil {
+			if err := s2.getGoType(g, required); err != nil {
 				return errors.WithMessage(err, "keyword '$ref'")
 			}
 			s.specialType = s2.specialType
@@ -267,67 +448,506 @@ func (s *jsonSchema) getGoType(schemas map[string]*jsonSchema, required bool) er
 		return nil
 	}
 
+	if values, ok := s.stringEnum(); ok && g.opts.Enums && s.typeName != "" {
+		s.specialType = isEnum
+		s.goType = "string"
+		return errors.WithMessage(g.addEnum(s, values), "keyword 'enum'")
+	}
+
+	// Patterns which are not valid go regular expressions, such as those with lookarounds, remain plain strings.
+	if t, ok := s.Type.(string); ok && t == "string" && s.Pattern != "" && g.opts.NewtypePatterns && isGoRegexp(s.Pattern) {
+		if s.typeName != "" {
+			s.specialType = isPattern
+			s.goType = "string"
+			g.addPatternType(s.typeName, s.Pattern, false)
+			return nil
+		} else if s.fieldName != "" {
+			if err := g.reserve(s.fieldName, "a property with the pattern "+s.Pattern); err != nil {
+				return errors.WithMessage(err, "keyword 'pattern'")
+			}
+			s.goType = s.fieldName
+			g.addPatternType(s.fieldName, s.Pattern, true)
+			return nil
+		}
+	}
+
 	if s.AnyOf != nil {
-		return errors.WithMessage(s.handleOneAnyAllOf(s.AnyOf, schemas, required, "anyOf"), "keyword 'anyOf'")
+		return errors.WithMessage(s.handleOneAnyAllOf(s.AnyOf, g, required, "anyOf"), "keyword 'anyOf'")
 	}
 	if s.OneOf != nil {
-		return errors.WithMessage(s.handleOneAnyAllOf(s.OneOf, schemas, required, "oneOf"), "keyword 'oneOf'")
+		return errors.WithMessage(s.handleOneAnyAllOf(s.OneOf, g, required, "oneOf"), "keyword 'oneOf'")
 	}
 	if s.AllOf != nil {
-		return errors.WithMessage(s.handleOneAnyAllOf(s.AllOf, schemas, required, "allOf"), "keyword 'allOf'")
+		return errors.WithMessage(s.handleOneAnyAllOf(s.AllOf, g, required, "allOf"), "keyword 'allOf'")
 	}
 	if s.Type != nil {
-		return errors.WithMessage(s.handleType(s.Type, schemas, required), "keyword 'type'")
+		return errors.WithMessage(s.handleType(s.Type, g, required), "keyword 'type'")
 	}
 	s.goType = "interface{}"
 	return nil
 }
 
+// Generate go source code for models of the given schemas in the package 'packageName'.
 func Generate(packageName string, schemas map[string][]byte) ([]byte, error) {
-	var b bytes.Buffer
+	return GenerateWithOptions(packageName, schemas, GenerateOptions{})
+}
+
+// Generate go source code for models of the given schemas in the package 'packageName',
+// enabling any additional features which are set in 'opts'.
+func GenerateWithOptions(packageName string, schemas map[string][]byte, opts GenerateOptions) ([]byte, error) {
+	g, err := newGenerator(schemas, opts)
+	if err != nil {
+		return nil, err
+	}
+	return g.generate(packageName)
+}
 
-	jsonSchemas := make(map[string]*jsonSchema, len(schemas))
+// Generate go source code for models of the given schemas in the package 'packageName', split into multiple files.
+// Returns a mapping of file name to source, where each top-level type and its methods are written to a file
+// named with the prefix "zz_generated." so that generated files are easily identified and sort last.
+func GenerateFiles(packageName string, schemas map[string][]byte, opts GenerateOptions) (map[string][]byte, error) {
+	g, err := newGenerator(schemas, opts)
+	if err != nil {
+		return nil, err
+	}
+	return g.generateFiles(packageName)
+}
+
+// Generate go source code for models of the given schemas in the package 'packageName',
+// along with a function 'RegisterSchemas(b vjsonschema.Builder) error' which adds each of the schemas to a builder.
+// This allows the generated package to be the single source of truth for both the models and their validation.
+func GenerateWithRegistry(packageName string, schemas map[string][]byte, opts GenerateOptions) ([]byte, error) {
+	g, err := newGenerator(schemas, opts)
+	if err != nil {
+		return nil, err
+	}
+	g.addRegistry(schemas)
+	return g.generate(packageName)
+}
+
+func newGenerator(schemas map[string][]byte, opts GenerateOptions) (*generator, error) {
+	g := &generator{
+		opts:    opts,
+		schemas: make(map[string]*jsonSchema, len(schemas)),
+		decls:   make(map[string]map[string]decl),
+		names:   make(map[string]string),
+	}
 	for name, schema := range schemas {
 		s := new(jsonSchema)
 		if err := json.Unmarshal(schema, s); err != nil {
 			return nil, errors.WithMessage(err, "failed to marshal schema into json "+name)
 		}
-		jsonSchemas[name] = s
+		s.typeName = name
+		g.schemas[name] = s
 	}
+	return g, nil
+}
 
-	b.WriteString("package " + packageName + "\n")
+// A section of the generated source, holding a single type and the declarations which belong to it.
+type chunk struct {
+	name    string
+	src     bytes.Buffer
+	imports map[string]struct{}
+}
 
-	names := make([]string, 0, len(jsonSchemas))
-	for name := range jsonSchemas {
+func (g *generator) generate(packageName string) ([]byte, error) {
+	chunks, err := g.chunks()
+	if err != nil {
+		return nil, err
+	}
+	var body bytes.Buffer
+	imports := make(map[string]struct{})
+	for _, c := range chunks {
+		body.Write(c.src.Bytes())
+		for i := range c.imports {
+			imports[i] = struct{}{}
+		}
+	}
+	return g.file(packageName, imports, body.Bytes(), true)
+}
+
+// Generate a file for each chunk, named with the zz_generated. prefix.
+// If there is a package doc, it is written to its own file.
+func (g *generator) generateFiles(packageName string) (map[string][]byte, error) {
+	chunks, err := g.chunks()
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string][]byte, len(chunks)+1)
+	if g.opts.PackageDoc != "" {
+		if files["zz_generated.doc.go"], err = g.file(packageName, nil, nil, true); err != nil {
+			return nil, err
+		}
+	}
+	for _, c := range chunks {
+		name := "zz_generated." + strings.ToLower(c.name) + ".go"
+		if _, ok := files[name]; ok {
+			return nil, errors.New("multiple models would be written to the file: " + name)
+		}
+		if files[name], err = g.file(packageName, c.imports, c.src.Bytes(), false); err != nil {
+			return nil, errors.WithMessage(err, "file "+name)
+		}
+	}
+	return files, nil
+}
+
+// Determine the types of all schemas, then split the source into a chunk for every type and its declarations.
+func (g *generator) chunks() ([]*chunk, error) {
+	names := make([]string, 0, len(g.schemas))
+	for name := range g.schemas {
 		names = append(names, name)
 	}
 	sort.Strings(names)
 	for _, name := range names {
-		s := jsonSchemas[name]
-		if err := s.getGoType(jsonSchemas, true); err != nil {
+		s := g.schemas[name]
+		if err := s.getGoType(g, true); err != nil {
 			return nil, errors.WithMessage(err, "failed to get type of schema "+name)
 		}
+	}
+	if g.opts.RedactWriteOnly {
+		for _, name := range names {
+			g.addRedactedString(g.schemas[name])
+		}
+	}
+
+	chunks := make([]*chunk, 0, len(names)+len(g.decls))
+	for _, name := range names {
+		s := g.schemas[name]
+		c := newChunk(name)
 		if s.Title != "" {
-			b.WriteString("// " + s.Title + "\n")
+			c.src.WriteString("// " + s.Title + "\n")
 		}
 		if s.Description != "" {
-			b.WriteString("// " + s.Description + "\n")
+			c.src.WriteString("// " + s.Description + "\n")
 		}
 		if !s.canBeReferenced() {
-			b.WriteString("// ")
+			c.src.WriteString("// ")
+		}
+		c.src.WriteString(fmt.Sprintf("type %s %s\n\n", name, s.goType))
+		g.writeDecls(c)
+		chunks = append(chunks, c)
+	}
+	declNames := make([]string, 0, len(g.decls))
+	for name := range g.decls {
+		if _, ok := g.schemas[name]; !ok {
+			declNames = append(declNames, name)
+		}
+	}
+	sort.Strings(declNames)
+	for _, name := range declNames {
+		c := newChunk(name)
+		g.writeDecls(c)
+		chunks = append(chunks, c)
+	}
+	return chunks, nil
+}
+
+func newChunk(typeName string) *chunk {
+	return &chunk{name: typeName, imports: make(map[string]struct{})}
+}
+
+// Write the declarations belonging to the chunk's type into the chunk.
+func (g *generator) writeDecls(c *chunk) {
+	for _, d := range g.sortedDecls(c.name) {
+		c.src.WriteString(d.src + "\n\n")
+		for _, i := range d.imports {
+			c.imports[i] = struct{}{}
+		}
+	}
+}
+
+// Assemble a formatted go source file, including the package doc if 'doc' is true.
+func (g *generator) file(packageName string, imports map[string]struct{}, body []byte, doc bool) ([]byte, error) {
+	var out bytes.Buffer
+	if doc && g.opts.PackageDoc != "" {
+		for _, line := range strings.Split(strings.TrimSpace(g.opts.PackageDoc), "\n") {
+			out.WriteString(strings.TrimSpace("// "+line) + "\n")
 		}
-		b.WriteString(fmt.Sprintf("type %s %s\n\n", name, s.goType))
 	}
+	out.WriteString("package " + packageName + "\n")
+	if len(imports) > 0 {
+		x := make([]string, 0, len(imports))
+		for i := range imports {
+			x = append(x, i)
+		}
+		sort.Strings(x)
+		out.WriteString("\nimport (\n\t\"" + strings.Join(x, "\"\n\t\"") + "\"\n)\n\n")
+	}
+	out.Write(body)
 
-	if src, err := format.Source(b.Bytes()); err != nil {
+	if src, err := format.Source(out.Bytes()); err != nil {
 		return src, errors.WithMessage(err, "failed to parse models as go source")
 	} else {
 		return src, nil
 	}
 }
 
+// Add declarations of the given kind which belong to the type, replacing any previously added declarations of that kind.
+// Any packages which the declarations use must be listed in 'imports'.
+func (g *generator) addDecl(typeName, kind, src string, imports ...string) {
+	if _, ok := g.decls[typeName]; !ok {
+		g.decls[typeName] = make(map[string]decl, 1)
+	}
+	g.decls[typeName][kind] = decl{src: src, imports: imports}
+}
+
+func (g *generator) sortedDecls(typeName string) []decl {
+	kinds := make([]string, 0, len(g.decls[typeName]))
+	for kind := range g.decls[typeName] {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	out := make([]decl, 0, len(kinds))
+	for _, kind := range kinds {
+		out = append(out, g.decls[typeName][kind])
+	}
+	return out
+}
+
+// Add the RegisterSchemas function, which adds the source of each schema to a vjsonschema.Builder.
+func (g *generator) addRegistry(schemas map[string][]byte) {
+	names := make([]string, 0, len(schemas))
+	for name := range schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	b.WriteString(`// RegisterSchemas adds each of the schemas that these models were generated from to the builder.
+func RegisterSchemas(b vjsonschema.Builder) error {
+	schemas := []struct {
+		name   string
+		source string
+	}{
+`)
+	for _, name := range names {
+		b.WriteString(fmt.Sprintf("{%q, %s},\n", name, toStringLiteral(string(schemas[name]))))
+	}
+	b.WriteString(`}
+	for _, s := range schemas {
+		if err := b.AddSchema(s.name, []byte(s.source)); err != nil {
+			return err
+		}
+	}
+	return nil
+}`)
+	g.addDecl("RegisterSchemas", "registry", b.String(), "github.com/tjbrockmeyer/vjsonschema")
+}
+
+// Add a string type with an UnmarshalJSON method which enforces the pattern.
+// If 'declare' is true, the type itself is also declared.
+func (g *generator) addPatternType(typeName, pattern string, declare bool) {
+	varName := strings.ToLower(typeName[:1]) + typeName[1:] + "Pattern"
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("var %s = regexp.MustCompile(%s)\n\n", varName, toStringLiteral(pattern)))
+	if declare {
+		b.WriteString(fmt.Sprintf("// %s is a string which must match the pattern %s\ntype %s string\n\n", typeName, pattern, typeName))
+	}
+	b.WriteString(fmt.Sprintf(`func (p *%[1]s) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	if !%[2]s.MatchString(s) {
+		return fmt.Errorf("value for %[1]s does not match pattern %%s: %%q", %[2]s, s)
+	}
+	*p = %[1]s(s)
+	return nil
+}`, typeName, varName))
+	g.addDecl(typeName, "pattern", b.String(), "encoding/json", "fmt", "regexp")
+}
+
+// Add a String method to the type, formatted like the %+v verb, which redacts any writeOnly fields.
+// Objects are only given a String method where it differs from the default formatting,
+// while arrays and maps are only given one if their items contain writeOnly properties.
+func (g *generator) addRedactedString(s *jsonSchema) {
+	if s.specialType != isObject {
+		if s.canBeReferenced() && g.containsWriteOnly(s, make(map[*jsonSchema]struct{})) {
+			g.addDecl(s.typeName, "string", fmt.Sprintf(`// String redacts the %[1]s entirely, as its items contain writeOnly fields.
+func (m %[1]s) String() string {
+	return "***"
+}`, s.typeName))
+		}
+		return
+	}
+	format, args, custom := g.structFormat(s, "m")
+	if !custom {
+		return
+	}
+	value := fmt.Sprintf("%q", format)
+	if len(args) > 0 {
+		value = fmt.Sprintf("fmt.Sprintf(%q, %s)", format, strings.Join(args, ", "))
+	}
+	g.addDecl(s.typeName, "string", fmt.Sprintf(`// String formats the %[1]s with its writeOnly fields redacted.
+func (m %[1]s) String() string {
+	return %[2]s
+}`, s.typeName, value), "fmt")
+}
+
+// Builds the format string and arguments which print the struct value 'expr' of the schema like the %+v verb,
+// recursing into nested structs so that their writeOnly fields are also redacted.
+// Returns true as 'custom' if the result differs from the default formatting of the struct.
+func (g *generator) structFormat(s *jsonSchema, expr string) (format string, args []string, custom bool) {
+	parts := make([]string, 0, len(s.fields))
+	var add func(s *jsonSchema)
+	add = func(s *jsonSchema) {
+		if s.ofSchemas != nil {
+			for _, sub := range s.ofSchemas {
+				if ref, _ := isCompliantRef(sub.Ref); g.schemas[ref] != nil {
+					// embedded types are formatted as a field, rather than promoting their String method.
+					name := toIdentifier(ref)
+					parts = append(parts, name+":%+v")
+					args = append(args, expr+"."+name)
+					custom = true
+				} else {
+					add(sub)
+				}
+			}
+			return
+		}
+		for _, f := range s.fields {
+			name := toIdentifier(f.name)
+			if f.schema.WriteOnly {
+				parts = append(parts, name+":***")
+				custom = true
+			} else if f.schema.Ref == "" && f.schema.specialType == isObject && strings.HasPrefix(f.schema.goType, "struct{") {
+				nested, nestedArgs, nestedCustom := g.structFormat(f.schema, expr+"."+name)
+				parts = append(parts, name+":"+nested)
+				args = append(args, nestedArgs...)
+				custom = custom || nestedCustom
+			} else if g.containsWriteOnly(f.schema, make(map[*jsonSchema]struct{})) {
+				parts = append(parts, name+":***")
+				custom = true
+			} else {
+				parts = append(parts, name+":%+v")
+				args = append(args, expr+"."+name)
+			}
+		}
+	}
+	add(s)
+	return "{" + strings.Join(parts, " ") + "}", args, custom
+}
+
+// Returns true if a value of the schema may contain a writeOnly property,
+// other than within a named object, which redacts its own writeOnly properties.
+func (g *generator) containsWriteOnly(s *jsonSchema, seen map[*jsonSchema]struct{}) bool {
+	if s == nil {
+		return false
+	}
+	if s.WriteOnly {
+		return true
+	}
+	if _, ok := seen[s]; ok {
+		return false
+	}
+	seen[s] = struct{}{}
+	if ref, ok := isCompliantRef(s.Ref); ok {
+		target, ok := g.schemas[ref]
+		return ok && target.specialType != isObject && g.containsWriteOnly(target, seen)
+	}
+	subs := []*jsonSchema{s.AdditionalProperties}
+	for _, sub := range s.Properties {
+		subs = append(subs, sub)
+	}
+	for _, sub := range s.PatternProperties {
+		subs = append(subs, sub)
+	}
+	subs = append(subs, s.AllOf...)
+	subs = append(subs, s.AnyOf...)
+	subs = append(subs, s.OneOf...)
+	if s.Items != nil {
+		b, _ := json.Marshal(s.Items)
+		var items []*jsonSchema
+		if err := json.Unmarshal(b, &items); err != nil {
+			item := new(jsonSchema)
+			if err = json.Unmarshal(b, item); err == nil {
+				items = []*jsonSchema{item}
+			}
+		}
+		subs = append(subs, items...)
+	}
+	for _, sub := range subs {
+		if g.containsWriteOnly(sub, seen) {
+			return true
+		}
+	}
+	return false
+}
+
+// Add a string enum type for the schema along with its constants and methods.
+func (g *generator) addEnum(s *jsonSchema, values []string) error {
+	consts := make([]string, 0, len(values))
+	var b strings.Builder
+	b.WriteString("const (\n")
+	seen := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		if _, ok := seen[v]; ok {
+			continue
+		}
+		seen[v] = struct{}{}
+		c := s.typeName + toConstName(v)
+		if err := g.reserve(c, fmt.Sprintf("the constant for enum value %q of %s", v, s.typeName)); err != nil {
+			return err
+		}
+		consts = append(consts, c)
+		b.WriteString(fmt.Sprintf("%s %s = %q\n", c, s.typeName, v))
+	}
+	b.WriteString(")\n\n")
+	cases := strings.Join(consts, ", ")
+	b.WriteString(fmt.Sprintf(`func (e %[1]s) MarshalText() ([]byte, error) {
+	return []byte(e), nil
+}
+
+func (e *%[1]s) UnmarshalText(b []byte) error {
+	switch v := %[1]s(b); v {
+	case %[2]s:
+		*e = v
+		return nil
+	}
+	return fmt.Errorf("invalid value for %[1]s: %%q", string(b))
+}`, s.typeName, cases))
+	g.addDecl(s.typeName, "enum", b.String(), "fmt")
+	return nil
+}
+
+// Reserve an identifier which is declared in addition to the types of the schemas.
+// Returns an error if it is the name of a schema, or if it has already been reserved by a different 'owner'.
+func (g *generator) reserve(name, owner string) error {
+	if _, ok := g.schemas[name]; ok {
+		return fmt.Errorf("%s is declared for %s, but is already the name of a schema", name, owner)
+	}
+	if other, ok := g.names[name]; ok && other != owner {
+		return fmt.Errorf("%s is declared for both %s and %s", name, other, owner)
+	}
+	g.names[name] = owner
+	return nil
+}
+
 func (s *jsonSchema) canBeReferenced() bool {
-	return s.specialType == isObject || s.specialType == isArray
+	return s.specialType == isObject || s.specialType == isArray || s.specialType == isEnum || s.specialType == isPattern
+}
+
+// Returns the values of the 'enum' keyword if the schema is a string which only allows string values.
+func (s *jsonSchema) stringEnum() ([]string, bool) {
+	if t, ok := s.Type.(string); !ok || t != "string" || len(s.Enum) == 0 {
+		return nil, false
+	}
+	values := make([]string, 0, len(s.Enum))
+	for _, e := range s.Enum {
+		v, ok := e.(string)
+		if !ok {
+			return nil, false
+		}
+		values = append(values, v)
+	}
+	return values, true
+}
+
+func isGoRegexp(pattern string) bool {
+	_, err := regexp.Compile(pattern)
+	return err == nil
 }
 
 func toIdentifier(s string) string {
@@ -340,6 +960,27 @@ func toIdentifier(s string) string {
 	return strings.ToUpper(s[:1]) + s[1:]
 }
 
+func toConstName(s string) string {
+	var b strings.Builder
+	for _, word := range nonWordRegex.Split(s, -1) {
+		if word != "" {
+			b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+		}
+	}
+	if b.Len() == 0 {
+		return "Empty"
+	}
+	return b.String()
+}
+
+// Returns a go raw string literal of s where possible, otherwise an interpreted string literal.
+func toStringLiteral(s string) string {
+	if strings.Contains(s, "`") {
+		return strconv.Quote(s)
+	}
+	return "`" + s + "`"
+}
+
 func isCompliantRef(ref string) (r string, ok bool) {
 	if strings.HasPrefix(ref, "{") && strings.HasSuffix(ref, "}") {
 		return ref[1 : len(ref)-1], true
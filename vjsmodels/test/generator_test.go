@@ -1,7 +1,375 @@
 package test
 
-import "testing"
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/tjbrockmeyer/vjsonschema"
+	"github.com/tjbrockmeyer/vjsonschema/vjsmodels"
+	"github.com/tjbrockmeyer/vjsonschema/vjsmodels/test/models"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite ./models/models.go using the current generator")
+
+// The options used to generate ./models/models.go with GenerateWithRegistry.
+var modelOptions = vjsmodels.GenerateOptions{
+	Enums:           true,
+	RedactWriteOnly: true,
+	RequiredFirst:   true,
+	NewtypePatterns: true,
+}
+
+func getSchemas(t *testing.T) map[string][]byte {
+	t.Helper()
+	builder := vjsonschema.NewBuilder()
+	if err := builder.AddDir("./schemas"); err != nil {
+		t.Fatal(err)
+	}
+	return builder.GetSchemas()
+}
 
 func TestGenerator(t *testing.T) {
+	src, err := vjsmodels.GenerateWithRegistry("models", getSchemas(t), modelOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *update {
+		if err = ioutil.WriteFile("./models/models.go", src, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	golden, err := ioutil.ReadFile("./models/models.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(src, golden) {
+		t.Error("generated models do not match ./models/models.go - run the tests with -update to regenerate them")
+	}
+}
+
+func TestEnums(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		src, err := vjsmodels.Generate("models", getSchemas(t))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(src), "// type Color string") || strings.Contains(string(src), "MarshalText") {
+			t.Error("expected enums to not be generated without the option enabled")
+		}
+	})
+	t.Run("map key round trip", func(t *testing.T) {
+		in := map[models.Color]int{models.ColorRed: 1, models.ColorDarkBlue: 2}
+		b, err := json.Marshal(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != `{"dark-blue":2,"red":1}` {
+			t.Error("unexpected json:", string(b))
+		}
+		out := make(map[models.Color]int)
+		if err = json.Unmarshal(b, &out); err != nil {
+			t.Fatal(err)
+		}
+		if len(out) != 2 || out[models.ColorRed] != 1 || out[models.ColorDarkBlue] != 2 {
+			t.Error("unexpected map after round trip:", out)
+		}
+		if err = json.Unmarshal([]byte(`{"purple":3}`), &out); err == nil {
+			t.Error("expected an error when unmarshalling a key outside of the enum")
+		}
+	})
+	t.Run("struct field", func(t *testing.T) {
+		var e models.Enums
+		if err := json.Unmarshal([]byte(`{"primary":"green"}`), &e); err != nil {
+			t.Fatal(err)
+		}
+		if e.Primary != models.ColorGreen {
+			t.Error("expected primary to be green, found:", e.Primary)
+		}
+		if err := json.Unmarshal([]byte(`{"primary":"purple"}`), &e); err == nil {
+			t.Error("expected an error when unmarshalling a value outside of the enum")
+		}
+		if b, err := json.Marshal(models.Enums{}); err != nil {
+			t.Error("expected the zero value to be marshalled:", err)
+		} else if !strings.Contains(string(b), `"primary":""`) {
+			t.Error("unexpected json:", string(b))
+		}
+	})
+	t.Run("colliding wrapper", func(t *testing.T) {
+		schemas := getSchemas(t)
+		schemas["ShapesItem"] = []byte(`{"type":"object","properties":{"x":{"type":"string"}}}`)
+		if _, err := vjsmodels.Generate("models", schemas); err == nil || !strings.Contains(err.Error(), "ShapesItem") {
+			t.Error("expected an error for a discriminated array wrapper with the name of a schema, found:", err)
+		}
+	})
+	t.Run("colliding constants", func(t *testing.T) {
+		for _, enum := range []string{`["a-b","a_b"]`, `["foo","Foo"]`} {
+			schemas := map[string][]byte{"E": []byte(`{"type":"string","enum":` + enum + `}`)}
+			if _, err := vjsmodels.GenerateWithOptions("models", schemas, vjsmodels.GenerateOptions{Enums: true}); err == nil {
+				t.Error("expected an error for enum values with the same constant name:", enum)
+			}
+		}
+		schemas := map[string][]byte{
+			"E":   []byte(`{"type":"string","enum":["a","b"]}`),
+			"EA":  []byte(`{"type":"string"}`),
+			"Dup": []byte(`{"type":"string","enum":["a","a"]}`),
+		}
+		if _, err := vjsmodels.GenerateWithOptions("models", schemas, vjsmodels.GenerateOptions{Enums: true}); err == nil || !strings.Contains(err.Error(), "EA") {
+			t.Error("expected an error for a constant with the name of a schema, found:", err)
+		}
+		delete(schemas, "EA")
+		if _, err := vjsmodels.GenerateWithOptions("models", schemas, vjsmodels.GenerateOptions{Enums: true}); err != nil {
+			t.Error("expected repeated enum values to be declared once:", err)
+		}
+	})
+}
+
+func TestDiscriminatedArray(t *testing.T) {
+	in := `[{"kind":"circle","radius":1.5},{"kind":"square","side":2}]`
+	var shapes models.Shapes
+	if err := json.Unmarshal([]byte(in), &shapes); err != nil {
+		t.Fatal(err)
+	}
+	if len(shapes) != 2 {
+		t.Fatal("expected 2 shapes, found:", len(shapes))
+	}
+	if c, ok := shapes[0].Value.(*models.Circle); !ok || c.Radius != 1.5 {
+		t.Errorf("expected the first shape to be a circle with radius 1.5, found: %#v", shapes[0].Value)
+	}
+	if s, ok := shapes[1].Value.(*models.Square); !ok || s.Side != 2 {
+		t.Errorf("expected the second shape to be a square with side 2, found: %#v", shapes[1].Value)
+	}
+	out, err := json.Marshal(shapes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != in {
+		t.Error("expected round trip to produce the original json, found:", string(out))
+	}
+	if err = json.Unmarshal([]byte(`[{"kind":"triangle"}]`), &shapes); err == nil {
+		t.Error("expected an error when unmarshalling an unknown variant")
+	}
+}
+
+func TestRedactWriteOnly(t *testing.T) {
+	t.Run("generated", func(t *testing.T) {
+		schemas := getSchemas(t)
+		src, err := vjsmodels.GenerateWithOptions("models", map[string][]byte{"Login": schemas["Login"]}, vjsmodels.GenerateOptions{RedactWriteOnly: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := `// String formats the Login with its writeOnly fields redacted.
+func (m Login) String() string {
+	return fmt.Sprintf("{Password:*** Remember:%+v Username:%+v}", m.Remember, m.Username)
+}`
+		if !strings.Contains(string(src), expected) {
+			t.Errorf("expected generated source to contain:\n%s\nfound:\n%s", expected, src)
+		}
+		src, err = vjsmodels.Generate("models", map[string][]byte{"Login": schemas["Login"]})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(src), "String()") {
+			t.Error("expected no String method without the option enabled")
+		}
+	})
+	t.Run("runtime", func(t *testing.T) {
+		login := models.Login{Username: "bob", Password: "hunter2", Remember: true}
+		for _, s := range []string{fmt.Sprint(login), fmt.Sprintf("%v", &login), login.String()} {
+			if s != "{Password:*** Username:bob Remember:true}" {
+				t.Error("expected the password to be redacted, found:", s)
+			}
+		}
+		if login.Password != "hunter2" {
+			t.Error("expected the password to remain accessible on the struct")
+		}
+	})
+	t.Run("nested", func(t *testing.T) {
+		var n models.Nested
+		n.Name = "x"
+		n.Creds.User = "bob"
+		n.Creds.Secret = "hunter2"
+		n.Keys = append(n.Keys, struct {
+			Key string `json:"key,omitempty"`
+		}{Key: "hunter3"})
+		if s := fmt.Sprint(n); s != "{Creds:{Secret:*** User:bob} Keys:*** Name:x}" {
+			t.Error("expected nested writeOnly fields to be redacted, found:", s)
+		}
+	})
+	t.Run("allOf", func(t *testing.T) {
+		a := models.Account{Login: &models.Login{Username: "bob", Password: "hunter2"}, Id: "42"}
+		if s := fmt.Sprint(a); s != "{Login:{Password:*** Username:bob Remember:false} Id:42}" {
+			t.Error("expected every field of the combined struct to be formatted, found:", s)
+		}
+		if s := fmt.Sprint(models.Account{Id: "42"}); s != "{Login:<nil> Id:42}" {
+			t.Error("expected a nil embedded type to be formatted as <nil>, found:", s)
+		}
+	})
+}
+
+func TestRequiredFirst(t *testing.T) {
+	schemas := getSchemas(t)
+	src, err := vjsmodels.GenerateWithOptions("models", map[string][]byte{"Login": schemas["Login"]}, vjsmodels.GenerateOptions{RequiredFirst: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `type Login struct {
+	Password string ` + "`json:\"password\"`" + `
+	Username string ` + "`json:\"username\"`" + `
+	Remember bool   ` + "`json:\"remember,omitempty\"`" + `
+}`
+	if !strings.Contains(string(src), expected) {
+		t.Errorf("expected generated source to contain:\n%s\nfound:\n%s", expected, src)
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	builder := vjsonschema.NewBuilder()
+	if err := models.RegisterSchemas(builder); err != nil {
+		t.Fatal(err)
+	}
+	expected := getSchemas(t)
+	registered := builder.GetSchemas()
+	if len(registered) != len(expected) {
+		t.Errorf("expected %d schemas to be registered, found %d", len(expected), len(registered))
+	}
+	for name, source := range expected {
+		if !bytes.Equal(registered[name], source) {
+			t.Errorf("expected schema %s to be registered with source %s, found %s", name, source, registered[name])
+		}
+	}
+	v, err := builder.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, err := v.Validate("Login", []byte(`{"username":"bob"}`)); err != nil {
+		t.Error(err)
+	} else if r.Valid() {
+		t.Error("expected the registered Login schema to require a password")
+	}
+	for instance, valid := range map[string]bool{
+		`{"home":{"street":"a"},"zip":"12345"}`: true,
+		`{"home":5}`:                            false,
+		`{"home":{}}`:                           false,
+		`{"zip":"abc"}`:                         false,
+	} {
+		if r, err := v.Validate("Home", []byte(instance)); err != nil {
+			t.Error(err)
+		} else if r.Valid() != valid {
+			t.Errorf("expected validity of %s against the registered Home schema to be %v", instance, valid)
+		}
+	}
+}
+
+func TestNewtypePatterns(t *testing.T) {
+	in := `{"phone":"+15555555555","name":"bob","zip":"12345"}`
+	var c models.Contact
+	if err := json.Unmarshal([]byte(in), &c); err != nil {
+		t.Fatal(err)
+	}
+	if c.Phone != models.ContactPhone("+15555555555") || c.Zip != models.ZipCode("12345") {
+		t.Error("unexpected contact after unmarshalling:", c)
+	}
+	out, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != `{"phone":"+15555555555","name":"bob","zip":"12345"}` {
+		t.Error("expected round trip to produce the original json, found:", string(out))
+	}
+	for _, invalid := range []string{`{"phone":"abc"}`, `{"phone":"+15555555555","zip":"1234"}`, `{"phone":5}`} {
+		if err = json.Unmarshal([]byte(invalid), &c); err == nil {
+			t.Error("expected an error when unmarshalling:", invalid)
+		}
+	}
+	c = models.Contact{Zip: "12345"}
+	if err = json.Unmarshal([]byte(`{"phone":"+15555555555","zip":null}`), &c); err != nil {
+		t.Error("expected null to be accepted like a plain string:", err)
+	} else if c.Zip != "12345" {
+		t.Error("expected null to leave the value unchanged, found:", c.Zip)
+	}
+	src, err := vjsmodels.Generate("models", getSchemas(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(src), "regexp") {
+		t.Error("expected no pattern types without the option enabled")
+	}
+
+	opts := vjsmodels.GenerateOptions{NewtypePatterns: true}
+	schemas := map[string][]byte{
+		"Contact":      []byte(`{"type":"object","properties":{"phone":{"type":"string","pattern":"^[0-9]+$"}}}`),
+		"ContactPhone": []byte(`{"type":"object","properties":{"number":{"type":"string"}}}`),
+	}
+	if _, err = vjsmodels.GenerateWithOptions("models", schemas, opts); err == nil || !strings.Contains(err.Error(), "ContactPhone") {
+		t.Error("expected an error for a pattern type with the name of a schema, found:", err)
+	}
+	schemas = map[string][]byte{
+		"Word": []byte(`{"type":"string","pattern":"^(?!x)"}`),
+		"Note": []byte(`{"type":"object","properties":{"text":{"type":"string","pattern":"^(?!x)"}}}`),
+	}
+	if src, err = vjsmodels.GenerateWithOptions("models", schemas, opts); err != nil {
+		t.Error("expected patterns which are not valid go regular expressions to be ignored:", err)
+	} else if strings.Contains(string(src), "regexp") || !strings.Contains(string(src), "Text string") {
+		t.Error("expected a plain string for a pattern which is not a valid go regular expression:\n", string(src))
+	}
+}
+
+func TestGenerateFiles(t *testing.T) {
+	opts := modelOptions
+	opts.PackageDoc = "Package models contains the generated models.\n\nDo not edit."
+	files, err := vjsmodels.GenerateFiles("models", getSchemas(t), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	expected := []string{
+		"zz_generated.account.go",
+		"zz_generated.addr.go",
+		"zz_generated.addrzip.go",
+		"zz_generated.circle.go",
+		"zz_generated.color.go",
+		"zz_generated.contact.go",
+		"zz_generated.contactphone.go",
+		"zz_generated.doc.go",
+		"zz_generated.enums.go",
+		"zz_generated.home.go",
+		"zz_generated.login.go",
+		"zz_generated.nested.go",
+		"zz_generated.shapes.go",
+		"zz_generated.square.go",
+		"zz_generated.zipcode.go",
+	}
+	if strings.Join(names, ",") != strings.Join(expected, ",") {
+		t.Error("unexpected file names:", names)
+	}
+	doc := "// Package models contains the generated models.\n//\n// Do not edit.\npackage models\n"
+	if string(files["zz_generated.doc.go"]) != doc {
+		t.Errorf("expected the doc file to be:\n%s\nfound:\n%s", doc, files["zz_generated.doc.go"])
+	}
+	for name, src := range files {
+		if name != "zz_generated.doc.go" && !bytes.HasPrefix(src, []byte("package models\n")) {
+			t.Errorf("expected %s to begin with the package clause, found:\n%s", name, src)
+		}
+	}
+	if !bytes.Contains(files["zz_generated.color.go"], []byte("import (\n\t\"fmt\"\n)")) {
+		t.Error("expected the enum file to import only fmt, found:\n", string(files["zz_generated.color.go"]))
+	}
 
+	src, err := vjsmodels.GenerateWithOptions("models", getSchemas(t), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(src, []byte(doc)) {
+		t.Error("expected the package doc to precede the package clause, found:\n", string(src))
+	}
 }
@@ -0,0 +1,232 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/tjbrockmeyer/vjsonschema"
+	"regexp"
+)
+
+type Account struct {
+	// allOf: schema #0
+	*Login
+	// allOf: schema #1
+	Id string `json:"id"`
+}
+
+// String formats the Account with its writeOnly fields redacted.
+func (m Account) String() string {
+	return fmt.Sprintf("{Login:%+v Id:%+v}", m.Login, m.Id)
+}
+
+type Addr struct {
+	Street string  `json:"street"`
+	Zip    AddrZip `json:"zip,omitempty"`
+}
+
+type Circle struct {
+	Kind   string  `json:"kind"`
+	Radius float64 `json:"radius"`
+}
+
+type Color string
+
+const (
+	ColorRed      Color = "red"
+	ColorGreen    Color = "green"
+	ColorDarkBlue Color = "dark-blue"
+)
+
+func (e Color) MarshalText() ([]byte, error) {
+	return []byte(e), nil
+}
+
+func (e *Color) UnmarshalText(b []byte) error {
+	switch v := Color(b); v {
+	case ColorRed, ColorGreen, ColorDarkBlue:
+		*e = v
+		return nil
+	}
+	return fmt.Errorf("invalid value for Color: %q", string(b))
+}
+
+type Contact struct {
+	Phone ContactPhone `json:"phone"`
+	Name  string       `json:"name,omitempty"`
+	Zip   ZipCode      `json:"zip,omitempty"`
+}
+
+type Enums struct {
+	Primary   Color `json:"primary"`
+	Secondary Color `json:"secondary,omitempty"`
+}
+
+type Home struct {
+	Home Addr        `json:"home,omitempty"`
+	Zip  interface{} `json:"zip,omitempty"`
+}
+
+type Login struct {
+	Password string `json:"password"`
+	Username string `json:"username"`
+	Remember bool   `json:"remember,omitempty"`
+}
+
+// String formats the Login with its writeOnly fields redacted.
+func (m Login) String() string {
+	return fmt.Sprintf("{Password:*** Username:%+v Remember:%+v}", m.Username, m.Remember)
+}
+
+type Nested struct {
+	Creds struct {
+		Secret string `json:"secret,omitempty"`
+		User   string `json:"user,omitempty"`
+	} `json:"creds,omitempty"`
+	Keys []struct {
+		Key string `json:"key,omitempty"`
+	} `json:"keys,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+// String formats the Nested with its writeOnly fields redacted.
+func (m Nested) String() string {
+	return fmt.Sprintf("{Creds:{Secret:*** User:%+v} Keys:*** Name:%+v}", m.Creds.User, m.Name)
+}
+
+type Shapes []ShapesItem
+
+// ShapesItem holds a single item of Shapes, whose variant is chosen by the "kind" property.
+// Value is one of: *Circle, *Square
+type ShapesItem struct {
+	Value interface{}
+}
+
+func (w ShapesItem) MarshalJSON() ([]byte, error) {
+	return json.Marshal(w.Value)
+}
+
+func (s *Shapes) UnmarshalJSON(b []byte) error {
+	var items []json.RawMessage
+	if err := json.Unmarshal(b, &items); err != nil {
+		return err
+	}
+	if items == nil {
+		*s = nil
+		return nil
+	}
+	out := make(Shapes, 0, len(items))
+	for i, item := range items {
+		var d struct {
+			Kind string `json:"kind"`
+		}
+		if err := json.Unmarshal(item, &d); err != nil {
+			return fmt.Errorf("item %d of Shapes: %v", i, err)
+		}
+		var v interface{}
+		switch d.Kind {
+		case "circle":
+			v = new(Circle)
+		case "square":
+			v = new(Square)
+		default:
+			return fmt.Errorf("item %d of Shapes: unknown value for property kind: %q", i, d.Kind)
+		}
+		if err := json.Unmarshal(item, v); err != nil {
+			return fmt.Errorf("item %d of Shapes: %v", i, err)
+		}
+		out = append(out, ShapesItem{Value: v})
+	}
+	*s = out
+	return nil
+}
+
+type Square struct {
+	Kind string  `json:"kind"`
+	Side float64 `json:"side"`
+}
+
+type ZipCode string
+
+var zipCodePattern = regexp.MustCompile(`^[0-9]{5}$`)
+
+func (p *ZipCode) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	if !zipCodePattern.MatchString(s) {
+		return fmt.Errorf("value for ZipCode does not match pattern %s: %q", zipCodePattern, s)
+	}
+	*p = ZipCode(s)
+	return nil
+}
+
+var addrZipPattern = regexp.MustCompile(`^[0-9]{5}$`)
+
+// AddrZip is a string which must match the pattern ^[0-9]{5}$
+type AddrZip string
+
+func (p *AddrZip) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	if !addrZipPattern.MatchString(s) {
+		return fmt.Errorf("value for AddrZip does not match pattern %s: %q", addrZipPattern, s)
+	}
+	*p = AddrZip(s)
+	return nil
+}
+
+var contactPhonePattern = regexp.MustCompile(`^\+?[0-9]{7,15}$`)
+
+// ContactPhone is a string which must match the pattern ^\+?[0-9]{7,15}$
+type ContactPhone string
+
+func (p *ContactPhone) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	if !contactPhonePattern.MatchString(s) {
+		return fmt.Errorf("value for ContactPhone does not match pattern %s: %q", contactPhonePattern, s)
+	}
+	*p = ContactPhone(s)
+	return nil
+}
+
+// RegisterSchemas adds each of the schemas that these models were generated from to the builder.
+func RegisterSchemas(b vjsonschema.Builder) error {
+	schemas := []struct {
+		name   string
+		source string
+	}{
+		{"Account", `{"allOf":[{"$ref":"{Login}"},{"properties":{"id":{"type":"string"}},"required":["id"],"type":"object"}]}`},
+		{"Addr", `{"$anchor":"addr","properties":{"street":{"type":"string"},"zip":{"$anchor":"zip","pattern":"^[0-9]{5}$","type":"string"}},"required":["street"],"type":"object"}`},
+		{"Circle", `{"properties":{"kind":{"enum":["circle"],"type":"string"},"radius":{"type":"number"}},"required":["kind","radius"],"type":"object"}`},
+		{"Color", `{"enum":["red","green","dark-blue"],"type":"string"}`},
+		{"Contact", `{"properties":{"name":{"type":"string"},"phone":{"pattern":"^\\+?[0-9]{7,15}$","type":"string"},"zip":{"$ref":"{ZipCode}"}},"required":["phone"],"type":"object"}`},
+		{"Enums", `{"properties":{"primary":{"$ref":"{Color}"},"secondary":{"$ref":"{Color}"}},"required":["primary"],"type":"object"}`},
+		{"Home", `{"properties":{"home":{"$ref":"{Addr}"},"zip":{"$ref":"{Addr}#/properties/zip"}},"type":"object"}`},
+		{"Login", `{"properties":{"password":{"type":"string","writeOnly":true},"remember":{"type":"boolean"},"username":{"type":"string"}},"required":["username","password"],"type":"object"}`},
+		{"Nested", `{"properties":{"creds":{"properties":{"secret":{"type":"string","writeOnly":true},"user":{"type":"string"}},"type":"object"},"keys":{"items":{"properties":{"key":{"type":"string","writeOnly":true}},"type":"object"},"type":"array"},"name":{"type":"string"}},"type":"object"}`},
+		{"Shapes", `{"items":{"discriminator":{"mapping":{"circle":"{Circle}","square":"{Square}"},"propertyName":"kind"},"oneOf":[{"$ref":"{Circle}"},{"$ref":"{Square}"}]},"type":"array"}`},
+		{"Square", `{"properties":{"kind":{"enum":["square"],"type":"string"},"side":{"type":"number"}},"required":["kind","side"],"type":"object"}`},
+		{"ZipCode", `{"pattern":"^[0-9]{5}$","type":"string"}`},
+	}
+	for _, s := range schemas {
+		if err := b.AddSchema(s.name, []byte(s.source)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,298 @@
+package vjsonschema
+
+import (
+	"encoding/json"
+	"github.com/pkg/errors"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Called for every schema which applies to a value in an instance.
+// Returning false will prevent the walk from continuing below the value.
+type schemaVisitor func(schema map[string]interface{}, instance interface{}, path []string) bool
+
+// Parses the source of the registered schema with the given name.
+// References to anchors which are not declared are replaced with "{#anchor}", which resolveRef reports as missing.
+func parseSchema(schemas map[string]registeredSchema, name string) (map[string]interface{}, error) {
+	s, ok := schemas[name]
+	if !ok {
+		return nil, errors.New("schema does not exist with name: " + name)
+	}
+	b := anchorRefRegex.ReplaceAllFunc(s.source, func(match []byte) []byte {
+		return []byte(`"$ref":"{#` + string(anchorRefRegex.FindSubmatch(match)[1]) + `}"`)
+	})
+	var m map[string]interface{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, errors.WithMessage(err, "failed to parse schema with name: "+name)
+	}
+	return m, nil
+}
+
+// Parses the schema referred to by a reference returned from compliantRef,
+// following the json pointer within the schema if there is one.
+func resolveRef(schemas map[string]registeredSchema, ref string) (map[string]interface{}, error) {
+	if strings.HasPrefix(ref, "#") {
+		return nil, errors.New("anchor does not exist with name: " + ref[1:])
+	}
+	parts := strings.SplitN(ref, "#", 2)
+	s, err := parseSchema(schemas, parts[0])
+	if err != nil || len(parts) == 1 {
+		return s, err
+	}
+	path := strings.Split(strings.TrimPrefix(parts[1], "/"), "/")
+	for i, p := range path {
+		path[i] = strings.Replace(strings.Replace(p, "~1", "/", -1), "~0", "~", -1)
+	}
+	if x, ok := lookupPath(s, path); ok {
+		if m, ok := x.(map[string]interface{}); ok {
+			return m, nil
+		}
+	}
+	return nil, errors.New("reference does not exist: " + ref)
+}
+
+// Follows compliant references until reaching a schema which is not a reference.
+func derefSchema(schemas map[string]registeredSchema, schema map[string]interface{}) map[string]interface{} {
+	seen := make(map[string]struct{}, 2)
+	for {
+		ref, ok := compliantRef(schema)
+		if !ok {
+			return schema
+		}
+		if _, ok := seen[ref]; ok {
+			return schema
+		}
+		seen[ref] = struct{}{}
+		s, err := resolveRef(schemas, ref)
+		if err != nil {
+			return schema
+		}
+		schema = s
+	}
+}
+
+// Walks the instance alongside the schema, visiting each value with every schema that applies to it.
+// Schemas are reached through references, properties, additionalProperties, items, allOf, anyOf and oneOf.
+func walkSchema(schemas map[string]registeredSchema, schema map[string]interface{}, instance interface{}, path []string, visit schemaVisitor) {
+	schema = derefSchema(schemas, schema)
+	if !visit(schema, instance, path) {
+		return
+	}
+	for _, kw := range []string{"allOf", "anyOf", "oneOf"} {
+		if subs, ok := schema[kw].([]interface{}); ok {
+			for _, sub := range subs {
+				if m, ok := sub.(map[string]interface{}); ok {
+					walkSchema(schemas, m, instance, path, visit)
+				}
+			}
+		}
+	}
+	switch inst := instance.(type) {
+	case map[string]interface{}:
+		props, _ := schema["properties"].(map[string]interface{})
+		additional, _ := schema["additionalProperties"].(map[string]interface{})
+		keys := make([]string, 0, len(inst))
+		for k := range inst {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if sub, ok := props[k].(map[string]interface{}); ok {
+				walkSchema(schemas, sub, inst[k], appendPath(path, k), visit)
+			} else if additional != nil {
+				walkSchema(schemas, additional, inst[k], appendPath(path, k), visit)
+			}
+		}
+	case []interface{}:
+		switch items := schema["items"].(type) {
+		case map[string]interface{}:
+			for i, item := range inst {
+				walkSchema(schemas, items, item, appendPath(path, strconv.Itoa(i)), visit)
+			}
+		case []interface{}:
+			for i, item := range inst {
+				if i >= len(items) {
+					break
+				}
+				if sub, ok := items[i].(map[string]interface{}); ok {
+					walkSchema(schemas, sub, item, appendPath(path, strconv.Itoa(i)), visit)
+				}
+			}
+		}
+	}
+}
+
+// Returns true if null is a valid instance of the schema, considering 'type', 'const', 'enum', 'allOf', 'anyOf' and 'oneOf'.
+func allowsNull(schemas map[string]registeredSchema, schema map[string]interface{}) bool {
+	schema = derefSchema(schemas, schema)
+	if c, ok := schema["const"]; ok && c != nil {
+		return false
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, e := range enum {
+			found = found || e == nil
+		}
+		if !found {
+			return false
+		}
+	}
+	switch t := schema["type"].(type) {
+	case string:
+		if t != "null" {
+			return false
+		}
+	case []interface{}:
+		found := false
+		for _, x := range t {
+			found = found || x == "null"
+		}
+		if !found {
+			return false
+		}
+	}
+	if subs, ok := schema["allOf"].([]interface{}); ok {
+		for _, sub := range subs {
+			if m, ok := sub.(map[string]interface{}); ok && !allowsNull(schemas, m) {
+				return false
+			}
+		}
+	}
+	for _, kw := range []string{"anyOf", "oneOf"} {
+		if subs, ok := schema[kw].([]interface{}); ok {
+			found := false
+			for _, sub := range subs {
+				if m, ok := sub.(map[string]interface{}); ok && allowsNull(schemas, m) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Determines the main type of value described by the schema, preferring any type other than null.
+func primaryType(schema map[string]interface{}) string {
+	switch t := schema["type"].(type) {
+	case string:
+		return t
+	case []interface{}:
+		for _, x := range t {
+			if s, ok := x.(string); ok && s != "null" {
+				return s
+			}
+		}
+		return "null"
+	}
+	if _, ok := schema["properties"]; ok {
+		return "object"
+	}
+	if _, ok := schema["items"]; ok {
+		return "array"
+	}
+	return "null"
+}
+
+// Returns the name of the schema referred to if the schema is a compliant reference.
+// References followed by a json pointer are returned as the name followed by the pointer, such as "Name#/properties/x".
+func compliantRef(schema map[string]interface{}) (string, bool) {
+	ref, _ := schema["$ref"].(string)
+	if len(ref) >= 2 && strings.HasPrefix(ref, "{") && strings.HasSuffix(ref, "}") {
+		return ref[1 : len(ref)-1], true
+	}
+	if i := strings.Index(ref, "}#/"); i > 0 && strings.HasPrefix(ref, "{") {
+		return ref[1:i] + ref[i+1:], true
+	}
+	return "", false
+}
+
+// Finds the value within the instance at the path, returning false if it does not exist.
+func lookupPath(instance interface{}, path []string) (interface{}, bool) {
+	for _, p := range path {
+		switch inst := instance.(type) {
+		case map[string]interface{}:
+			x, ok := inst[p]
+			if !ok {
+				return nil, false
+			}
+			instance = x
+		case []interface{}:
+			i, err := strconv.Atoi(p)
+			if err != nil || i < 0 || i >= len(inst) {
+				return nil, false
+			}
+			instance = inst[i]
+		default:
+			return nil, false
+		}
+	}
+	return instance, true
+}
+
+func appendPath(path []string, key string) []string {
+	return append(path[:len(path):len(path)], key)
+}
+
+// Formats a path the same way that gojsonschema formats the field of an error.
+func fieldName(path []string) string {
+	if len(path) == 0 {
+		return "(root)"
+	}
+	return strings.Join(path, ".")
+}
+
+// Returns a copy of the value with every compliant reference, or reference to an anchor, replaced by the schema that it refers to.
+// A reference to a schema which is already being inlined above it is replaced with a json pointer reference
+// to the location where that schema was inlined, breaking the cycle.
+func inlineRefs(schemas map[string]registeredSchema, value interface{}, pointer string, inlined map[string]string) (interface{}, error) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if ref, ok := compliantRef(v); ok {
+			if p, ok := inlined[ref]; ok {
+				return map[string]interface{}{"$ref": p}, nil
+			}
+			s, err := resolveRef(schemas, ref)
+			if err != nil {
+				return nil, err
+			}
+			inlined[ref] = pointer
+			out, err := inlineRefs(schemas, s, pointer, inlined)
+			delete(inlined, ref)
+			return out, err
+		}
+		out := make(map[string]interface{}, len(v))
+		for k, sub := range v {
+			if k == "$anchor" {
+				// every reference to an anchor is inlined, and the same anchor may now appear in many places.
+				continue
+			}
+			x, err := inlineRefs(schemas, sub, pointer+"/"+escapePointer(k), inlined)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = x
+		}
+		return out, nil
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, sub := range v {
+			x, err := inlineRefs(schemas, sub, pointer+"/"+strconv.Itoa(i), inlined)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = x
+		}
+		return out, nil
+	default:
+		return v, nil
+	}
+}
+
+func escapePointer(s string) string {
+	return strings.Replace(strings.Replace(s, "~", "~0", -1), "/", "~1", -1)
+}